@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym"
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// funcContext prints the context of the named function of the given SYM files;
+// i.e. the type definitions used by the function, its parameters and locals,
+// and declarations of the global variables it references, as a self-contained C
+// header in dependency order (e.g. for m2c).
+//
+// Note, the SYM does not record which global variables are referenced by a
+// function, so the global variables of the structure, union and enum types used
+// by the function are included instead, as located by the type cross-reference
+// index; except for static variables of other source files, which are not
+// visible to the function. Global variables of scalar types are not included.
+func funcContext(name string, symPaths []string, expand bool) error {
+	for _, symPath := range symPaths {
+		f, err := sym.ParseFile(symPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		p := csym.NewParser()
+		p.ExpandTypedefs = expand
+		p.ParseTypes(f.Syms)
+		if err := p.ParseDecls(f.Syms); err != nil {
+			return errors.WithStack(err)
+		}
+		p.NameFakeTags()
+		x := csym.NewXrefIndex(p)
+		if len(symPaths) > 1 {
+			fmt.Printf("// %s\n\n", symPath)
+		}
+		overlay, fn, err := findFunc(p, name)
+		if err != nil {
+			return errors.Wrapf(err, "unable to locate function in %q", symPath)
+		}
+		if err := dumpContext(os.Stdout, p, x, overlay, fn); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// findFunc returns the named function of the parser and its overlay. An error
+// is returned if no function or several functions (of different overlays) have
+// the given name.
+func findFunc(p *csym.Parser, name string) (*csym.Overlay, *c.FuncDecl, error) {
+	var overlays []*csym.Overlay
+	var funcs []*c.FuncDecl
+	for _, overlay := range append([]*csym.Overlay{p.Overlay}, p.Overlays...) {
+		for _, f := range overlay.Funcs {
+			if f.Name == name {
+				overlays = append(overlays, overlay)
+				funcs = append(funcs, f)
+			}
+		}
+	}
+	switch len(funcs) {
+	case 0:
+		return nil, nil, errors.Errorf("no function %q found", name)
+	case 1:
+		return overlays[0], funcs[0], nil
+	default:
+		var ids []string
+		for _, overlay := range overlays {
+			ids = append(ids, fmt.Sprintf("%x", overlay.ID))
+		}
+		return nil, nil, errors.Errorf("ambiguous function %q; defined in overlays %s", name, strings.Join(ids, ", "))
+	}
+}
+
+// dumpContext outputs the context of the given function of the overlay,
+// writing to w.
+func dumpContext(w io.Writer, p *csym.Parser, x *csym.XrefIndex, overlay *csym.Overlay, f *c.FuncDecl) error {
+	// Global variables of the structure, union and enum types used by the
+	// function, visible from the function.
+	refs := make(map[*c.VarDecl]bool)
+	for _, key := range x.FuncKeys(f) {
+		if x.Kind(key) == csym.KeyTypedef {
+			continue
+		}
+		for _, xref := range x.Lookup(key) {
+			if xref.Kind != csym.XrefGlobal {
+				continue
+			}
+			if v := xref.Var; v.Class == c.Static && len(v.Path) > 0 && v.Path != f.Path {
+				// Static variable of other source file.
+				continue
+			}
+			refs[xref.Var] = true
+		}
+	}
+	var vars []*c.VarDecl
+	nodes := []c.Node{f}
+	scopes := []*csym.Overlay{overlay}
+	if overlay != p.Overlay {
+		scopes = append(scopes, p.Overlay)
+	}
+	for _, scope := range scopes {
+		for _, v := range scope.Vars {
+			if refs[v] {
+				vars = append(vars, v)
+				nodes = append(nodes, v)
+			}
+		}
+	}
+	if _, err := fmt.Fprintf(w, "// context of %s (%s)\n\n", f.Name, f.Path); err != nil {
+		return errors.WithStack(err)
+	}
+	// Print predeclared identifiers.
+	if def, ok := p.Types["bool"]; ok {
+		if _, err := fmt.Fprintf(w, "%s;\n\n", def.Def()); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Print type definitions in dependency order.
+	for _, t := range p.ContextTypes(nodes...) {
+		if _, err := fmt.Fprintf(w, "%s;\n\n", t.Def()); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Print global variable declarations.
+	for _, v := range vars {
+		class := "extern"
+		if v.Class == c.Static {
+			class = "static"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s;\n", class, v.Var); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if len(vars) > 0 {
+		if _, err := fmt.Fprintln(w); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Print the prototype of the function.
+	if _, err := fmt.Fprintf(w, "%s;\n\n", f.Var); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+	"github.com/sanctuary/sym/symelf"
+)
+
+// --- [ Decompilation project ] -----------------------------------------------
+
+// Decompilation project file names.
+const (
+	// splat segment configuration.
+	splatName = "splat.yaml"
+	// Symbol addresses, sizes and types.
+	symbolAddrsName = "symbol_addrs.txt"
+	// Linker script of symbols without type information.
+	undefinedSymsName = "undefined_syms.txt"
+)
+
+// File offset of the code and data of PS-X EXE executables.
+const exeTextOffset = 0x800
+
+// dumpDecomp outputs the declarations recorded by the parser as the scaffolding
+// of a splat-based decompilation project, one set of files per overlay.
+//
+// Code segments are split into subsegments of the address ranges of functions
+// of each source file. File offsets are derived from the load address of the
+// given PS-X EXE executable, which is required as the code and data preceding
+// the first function are not described by the SYM.
+func dumpDecomp(p *csym.Parser, outputDir, exePath string) error {
+	if len(exePath) == 0 {
+		return errors.New("decompilation project output requires the PS-X EXE executable (-exe)")
+	}
+	buf, err := ioutil.ReadFile(exePath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	text, err := symelf.ParseEXE(buf)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse PS-X EXE %q", exePath)
+	}
+	basename := filepath.Base(exePath)
+	for _, overlay := range append([]*csym.Overlay{p.Overlay}, p.Overlays...) {
+		dir := outputDir
+		name := basename
+		if overlay.ID != 0 {
+			name = fmt.Sprintf("overlay_%x", overlay.ID)
+			dir = filepath.Join(outputDir, name)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if err := dumpSplat(overlay, name, dir, text); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpSymbolAddrs(overlay, dir); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpUndefinedSyms(overlay, dir); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// dumpSplat outputs the splat segment configuration of the overlay. The
+// default binary is described as the given PS-X EXE; overlays as raw binaries
+// loaded at the overlay address.
+func dumpSplat(overlay *csym.Overlay, name, dir string, text *symelf.Text) error {
+	splatPath := filepath.Join(dir, splatName)
+	fmt.Println("creating:", splatPath)
+	w, err := os.Create(splatPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create splat configuration %q", splatPath)
+	}
+	defer w.Close()
+	funcs := make([]*c.FuncDecl, len(overlay.Funcs))
+	copy(funcs, overlay.Funcs)
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].Addr < funcs[j].Addr
+	})
+	// Load address and file offset of code and data.
+	var vram, start, end uint32
+	if overlay.ID != 0 {
+		vram = overlay.Addr
+		end = overlay.Length
+	} else {
+		vram = text.Addr
+		start = exeTextOffset
+		end = start + uint32(len(text.Data))
+	}
+	offset := func(addr uint32) uint32 {
+		return start + addr - vram
+	}
+	if _, err := fmt.Fprintf(w, splatHeader[1:], name, name, name); err != nil {
+		return errors.WithStack(err)
+	}
+	if overlay.ID == 0 {
+		if _, err := io.WriteString(w, "  - name: header\n    type: header\n    start: 0x0\n"); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	segName := "main"
+	if overlay.ID != 0 {
+		segName = name
+	}
+	if _, err := fmt.Fprintf(w, "  - name: %s\n    type: code\n    start: 0x%X\n    vram: 0x%08X\n    subsegments:\n", segName, start, vram); err != nil {
+		return errors.WithStack(err)
+	}
+	// Subsegments of consecutive functions of the same source file, preceded
+	// by an assembly subsegment of code and data before the first function.
+	names := make(map[string]int)
+	prevPath := "\x00"
+	if len(funcs) == 0 || funcs[0].Addr > vram {
+		prevPath = ""
+		if _, err := fmt.Fprintf(w, "      - [0x%X, asm]\n", start); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, f := range funcs {
+		if f.Addr < vram || f.Path == prevPath {
+			continue
+		}
+		prevPath = f.Path
+		if len(f.Path) == 0 {
+			if _, err := fmt.Fprintf(w, "      - [0x%X, asm]\n", offset(f.Addr)); err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+		// Note, source files split into several address ranges are given
+		// unique subsegment names.
+		relPath := sourceRelPath(f.Path)
+		subName := strings.TrimSuffix(relPath, filepath.Ext(relPath))
+		names[subName]++
+		if n := names[subName]; n > 1 {
+			subName = fmt.Sprintf("%s_%d", subName, n)
+		}
+		if _, err := fmt.Fprintf(w, "      - [0x%X, c, %s]\n", offset(f.Addr), subName); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := fmt.Fprintf(w, "  - [0x%X]\n", end); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// splatHeader is the header of splat segment configurations, parameterized by
+// the binary name.
+const splatHeader = `
+name: %s
+options:
+  platform: psx
+  basename: %s
+  base_path: .
+  target_path: %s
+  asm_path: asm
+  src_path: src
+  build_path: build
+  compiler: GCC
+  symbol_addrs_path: symbol_addrs.txt
+  undefined_syms_auto_path: undefined_syms_auto.txt
+  undefined_funcs_auto_path: undefined_funcs_auto.txt
+segments:
+`
+
+// dumpSymbolAddrs outputs the functions and global variables of the overlay as
+// a splat symbol address list, with types and sizes.
+//
+//	DrawPlayer = 0x80012345; // type:func size:0x1A0
+//	plr = 0x80098765; // size:0x2B4
+func dumpSymbolAddrs(overlay *csym.Overlay, dir string) error {
+	addrsPath := filepath.Join(dir, symbolAddrsName)
+	fmt.Println("creating:", addrsPath)
+	w, err := os.Create(addrsPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create symbol address list %q", addrsPath)
+	}
+	defer w.Close()
+	type symAddr struct {
+		addr  uint32
+		name  string
+		attrs []string
+	}
+	var syms []symAddr
+	for _, f := range overlay.Funcs {
+		attrs := []string{"type:func"}
+		if f.Size > 0 {
+			attrs = append(attrs, fmt.Sprintf("size:0x%X", f.Size))
+		}
+		syms = append(syms, symAddr{addr: f.Addr, name: f.Name, attrs: attrs})
+	}
+	for _, v := range overlay.Vars {
+		var attrs []string
+		if typ, ok := splatType(v.Type); ok {
+			attrs = append(attrs, "type:"+typ)
+		}
+		if v.Size > 0 {
+			attrs = append(attrs, fmt.Sprintf("size:0x%X", v.Size))
+		}
+		syms = append(syms, symAddr{addr: v.Addr(), name: v.Name, attrs: attrs})
+	}
+	sort.SliceStable(syms, func(i, j int) bool {
+		return syms[i].addr < syms[j].addr
+	})
+	for _, s := range syms {
+		line := fmt.Sprintf("%s = 0x%08X;", s.name, s.addr)
+		if len(s.attrs) > 0 {
+			line += " // " + strings.Join(s.attrs, " ")
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// splatType returns the splat data type of the given type, if any.
+func splatType(t c.Type) (string, bool) {
+	switch t := t.(type) {
+	case c.BaseType:
+		switch t {
+		case c.Char:
+			return "s8", true
+		case c.UChar:
+			return "u8", true
+		case c.Short:
+			return "s16", true
+		case c.UShort:
+			return "u16", true
+		case c.Int, c.Long:
+			return "s32", true
+		case c.UInt, c.ULong:
+			return "u32", true
+		}
+	case *c.PointerType:
+		return "u32", true
+	case *c.TypedefType:
+		return splatType(t.Type)
+	case *c.VarDecl:
+		return splatType(t.Type)
+	}
+	return "", false
+}
+
+// dumpUndefinedSyms outputs the symbols of the overlay without type
+// information as a linker script of symbol assignments.
+func dumpUndefinedSyms(overlay *csym.Overlay, dir string) error {
+	symsPath := filepath.Join(dir, undefinedSymsName)
+	fmt.Println("creating:", symsPath)
+	w, err := os.Create(symsPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create undefined symbols linker script %q", symsPath)
+	}
+	defer w.Close()
+	names := make(map[string]bool)
+	for _, f := range overlay.Funcs {
+		names[f.Name] = true
+	}
+	for _, v := range overlay.Vars {
+		names[v.Name] = true
+	}
+	for _, s := range overlay.AddrSymbols() {
+		if names[s.Name] {
+			continue
+		}
+		names[s.Name] = true
+		if _, err := fmt.Fprintf(w, "%s = 0x%08X;\n", s.Name, s.Addr); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/symelf"
+)
+
+// --- [ ELF file ] ------------------------------------------------------------
+
+// dumpELF outputs the declarations and source line numbers recorded by the
+// parser to an ELF file with DWARF debug information. The code and data of the
+// ELF file are optionally taken from the given PS-X EXE executable.
+func dumpELF(p *csym.Parser, elfPath, exePath string) error {
+	var text *symelf.Text
+	if len(exePath) > 0 {
+		buf, err := ioutil.ReadFile(exePath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		text, err = symelf.ParseEXE(buf)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse PS-X EXE %q", exePath)
+		}
+	}
+	fmt.Println("creating:", elfPath)
+	w, err := os.Create(elfPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create ELF file %q", elfPath)
+	}
+	defer w.Close()
+	if err := symelf.Write(w, p, text); err != nil {
+		return errors.Wrapf(err, "unable to write ELF file %q", elfPath)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym/csym"
+)
+
+// --- [ Emulator symbol maps ] ------------------------------------------------
+
+// Note, only the PCSX-Redux symbol map is output. Its format records neither
+// symbol sizes nor source line numbers, and no symbol map formats of DuckStation
+// or no$psx are supported, as no loader of such formats has been verified. The
+// ELF output (-elf) records the symbol sizes and source line numbers instead,
+// as read by GDB attached to the GDB server of PCSX-Redux.
+
+// An emuSymbol is a symbol of an emulator symbol map.
+type emuSymbol struct {
+	// Symbol address.
+	Addr uint32
+	// Symbol name.
+	Name string
+}
+
+// An emuFormat is a text symbol map format of an emulator debugger.
+type emuFormat struct {
+	// Symbol map file name.
+	Name string
+	// writeSym writes a symbol to the symbol map.
+	writeSym func(w io.Writer, s *emuSymbol) error
+}
+
+// emuFormats lists the symbol map formats of emulator debuggers.
+var emuFormats = []*emuFormat{
+	// PCSX-Redux; one "address name" pair per line.
+	{
+		Name: "pcsx-redux.map",
+		writeSym: func(w io.Writer, s *emuSymbol) error {
+			_, err := fmt.Fprintf(w, "%08x %s\n", s.Addr, s.Name)
+			return err
+		},
+	},
+}
+
+// dumpEmuMaps outputs the symbols recorded by the parser to emulator symbol
+// maps, one set of maps per overlay.
+func dumpEmuMaps(p *csym.Parser, outputDir string) error {
+	for _, overlay := range append([]*csym.Overlay{p.Overlay}, p.Overlays...) {
+		dir := outputDir
+		if overlay.ID != 0 {
+			dir = filepath.Join(outputDir, fmt.Sprintf("overlay_%x", overlay.ID))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		syms := emuSymbols(overlay)
+		for _, format := range emuFormats {
+			if err := dumpEmuMap(format, syms, dir); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// dumpEmuMap outputs the given symbols to a symbol map of the given format.
+func dumpEmuMap(format *emuFormat, syms []*emuSymbol, dir string) error {
+	mapPath := filepath.Join(dir, format.Name)
+	fmt.Println("creating:", mapPath)
+	w, err := os.Create(mapPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create symbol map %q", mapPath)
+	}
+	defer w.Close()
+	for _, s := range syms {
+		if err := format.writeSym(w, s); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// emuSymbols returns the functions, global variables and symbols without type
+// information of the overlay, sorted by address. Only the first name is kept
+// of symbols sharing an address.
+func emuSymbols(overlay *csym.Overlay) []*emuSymbol {
+	var syms []*emuSymbol
+	addrs := make(map[uint32]bool)
+	for _, f := range overlay.Funcs {
+		addrs[f.Addr] = true
+		syms = append(syms, &emuSymbol{Addr: f.Addr, Name: f.Name})
+	}
+	for _, v := range overlay.Vars {
+		if addrs[v.Addr()] {
+			continue
+		}
+		addrs[v.Addr()] = true
+		syms = append(syms, &emuSymbol{Addr: v.Addr(), Name: v.Name})
+	}
+	for _, s := range overlay.AddrSymbols() {
+		if addrs[s.Addr] {
+			continue
+		}
+		addrs[s.Addr] = true
+		syms = append(syms, &emuSymbol{Addr: s.Addr, Name: s.Name})
+	}
+	sort.SliceStable(syms, func(i, j int) bool {
+		return syms[i].Addr < syms[j].Addr
+	})
+	return syms
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// --- [ Ghidra script ] -------------------------------------------------------
+
+// Ghidra script name.
+const ghidraScriptName = "import_psx.py"
+
+// dumpGhidraScript outputs the declarations recorded by the parser to a Ghidra
+// script, which loads types.h as a data type archive, creates overlay memory
+// blocks, labels and functions, and applies function prototypes, global
+// variable types and source line comments.
+func dumpGhidraScript(p *csym.Parser, outputDir string) error {
+	scriptPath := filepath.Join(outputDir, ghidraScriptName)
+	fmt.Println("creating:", scriptPath)
+	w, err := os.Create(scriptPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create Ghidra script %q", scriptPath)
+	}
+	defer w.Close()
+	if _, err := fmt.Fprintf(w, ghidraHeader[1:], pyString(typesName)); err != nil {
+		return errors.WithStack(err)
+	}
+	overlays := append([]*csym.Overlay{p.Overlay}, p.Overlays...)
+	// Overlay memory blocks.
+	if _, err := fmt.Fprintln(w, "OVERLAYS = ["); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, overlay := range p.Overlays {
+		if _, err := fmt.Fprintf(w, "\t(0x%X, 0x%08X, 0x%X),\n", overlay.ID, overlay.Addr, overlay.Length); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := fmt.Fprintln(w, "]\n\nLABELS = ["); err != nil {
+		return errors.WithStack(err)
+	}
+	// Labels of symbols without type information.
+	for _, overlay := range overlays {
+		addrs := make(map[uint32]bool)
+		for _, f := range overlay.Funcs {
+			addrs[f.Addr] = true
+		}
+		for _, v := range overlay.Vars {
+			addrs[v.Addr()] = true
+		}
+		for _, s := range overlay.AddrSymbols() {
+			if addrs[s.Addr] {
+				continue
+			}
+			addrs[s.Addr] = true
+			if _, err := fmt.Fprintf(w, "\t(0x%X, 0x%08X, %s),\n", overlay.ID, s.Addr, pyString(s.Name)); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	if _, err := fmt.Fprintln(w, "]\n\nFUNCS = ["); err != nil {
+		return errors.WithStack(err)
+	}
+	// Functions and prototypes.
+	for _, overlay := range overlays {
+		for _, f := range overlay.Funcs {
+			if _, err := fmt.Fprintf(w, "\t(0x%X, 0x%08X, %s, %s),\n", overlay.ID, f.Addr, pyString(f.Name), pyString(f.Var.String())); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	if _, err := fmt.Fprintln(w, "]\n\nVARS = ["); err != nil {
+		return errors.WithStack(err)
+	}
+	// Global variables and types.
+	for _, overlay := range overlays {
+		for _, v := range overlay.Vars {
+			typ := c.Var{Type: v.Type}.String()
+			if _, err := fmt.Fprintf(w, "\t(0x%X, 0x%08X, %s, %s),\n", overlay.ID, v.Addr(), pyString(v.Name), pyString(strings.TrimSpace(typ))); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	if _, err := fmt.Fprintln(w, "]\n\nLINES = ["); err != nil {
+		return errors.WithStack(err)
+	}
+	// Source line comments.
+	for _, overlay := range overlays {
+		if err := dumpGhidraLines(w, overlay); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := fmt.Fprintln(w, "]"); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.WriteString(w, ghidraMain); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// dumpGhidraLines outputs the source line comments of the overlay to the
+// Ghidra script. Comments of source lines sharing an address are joined.
+func dumpGhidraLines(w io.Writer, overlay *csym.Overlay) error {
+	var addrs []uint32
+	comments := make(map[uint32][]string)
+	for _, line := range overlay.Lines {
+		if _, ok := comments[line.Addr]; !ok {
+			addrs = append(addrs, line.Addr)
+		}
+		comment := fmt.Sprintf("%s:%d", line.Path, line.Line)
+		comments[line.Addr] = append(comments[line.Addr], comment)
+	}
+	for _, addr := range addrs {
+		comment := strings.Join(comments[addr], "\n")
+		if _, err := fmt.Fprintf(w, "\t(0x%X, 0x%08X, %s),\n", overlay.ID, addr, pyString(comment)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// pyString returns a Python unicode string literal of the given string, valid
+// in both Python 2 (Jython) and Python 3. Non-ASCII characters are escaped;
+// bytes of invalid UTF-8 encoding are interpreted as Latin-1.
+func pyString(s string) string {
+	buf := &strings.Builder{}
+	buf.WriteString(`u"`)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			r = rune(s[i])
+		}
+		i += size
+		switch {
+		case r == '\\' || r == '"':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case 0x20 <= r && r < 0x7F:
+			buf.WriteRune(r)
+		case r <= 0xFF:
+			fmt.Fprintf(buf, `\x%02x`, r)
+		case r <= 0xFFFF:
+			fmt.Fprintf(buf, `\u%04x`, r)
+		default:
+			fmt.Fprintf(buf, `\U%08x`, r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// ghidraHeader is the header of the Ghidra script, parameterized by the types
+// header file name.
+const ghidraHeader = `
+# Import Playstation 1 symbol information, as generated by sym_dump.
+#@category PSX
+
+import os
+
+from ghidra.app.cmd.function import ApplyFunctionSignatureCmd
+from ghidra.app.util.cparser.C import CParser
+from ghidra.app.util.parser import FunctionSignatureParser
+from ghidra.program.model.data import DataTypeConflictHandler, FileDataTypeManager
+from ghidra.program.model.listing import CodeUnit
+from ghidra.program.model.symbol import SourceType
+from ghidra.util.data import DataTypeParser
+from ghidra.util.data.DataTypeParser import AllowedDataTypes
+from java.io import File
+
+TYPES_H = %s
+TYPES_GDT = "types.gdt"
+
+`
+
+// ghidraMain is the body of the Ghidra script.
+const ghidraMain = `
+def load_types():
+	"""Parse the types header into a data type archive, and add its types to
+	the program."""
+	script_dir = os.path.dirname(getSourceFile().getAbsolutePath())
+	gdt = File(os.path.join(script_dir, TYPES_GDT))
+	if gdt.exists():
+		gdt.delete()
+	archive = FileDataTypeManager.createFileArchive(gdt)
+	with open(os.path.join(script_dir, TYPES_H)) as f:
+		CParser(archive).parse(f.read())
+	archive.save()
+	dtm = currentProgram.getDataTypeManager()
+	for dt in archive.getAllDataTypes():
+		dtm.addDataType(dt, DataTypeConflictHandler.REPLACE_HANDLER)
+	archive.close()
+
+def addr_of(overlay_id, addr):
+	"""Return the address in the given overlay."""
+	if overlay_id == 0:
+		return toAddr(addr)
+	space = currentProgram.getAddressFactory().getAddressSpace("overlay_%x" % overlay_id)
+	return space.getAddress(addr)
+
+def create_overlays():
+	memory = currentProgram.getMemory()
+	for overlay_id, addr, length in OVERLAYS:
+		name = "overlay_%x" % overlay_id
+		if memory.getBlock(name) is None:
+			memory.createUninitializedBlock(name, toAddr(addr), length, True)
+
+def create_labels():
+	for overlay_id, addr, name in LABELS:
+		createLabel(addr_of(overlay_id, addr), name, True, SourceType.IMPORTED)
+
+def create_funcs():
+	dtm = currentProgram.getDataTypeManager()
+	parser = FunctionSignatureParser(dtm, None)
+	for overlay_id, addr, name, proto in FUNCS:
+		a = addr_of(overlay_id, addr)
+		f = getFunctionAt(a)
+		if f is None:
+			f = createFunction(a, name)
+		if f is None:
+			createLabel(a, name, True, SourceType.IMPORTED)
+			continue
+		f.setName(name, SourceType.IMPORTED)
+		try:
+			sig = parser.parse(f.getSignature(), proto)
+			ApplyFunctionSignatureCmd(a, sig, SourceType.IMPORTED).applyTo(currentProgram)
+		except Exception as e:
+			print("unable to apply prototype %r at %s; %s" % (proto, a, e))
+
+def create_vars():
+	dtm = currentProgram.getDataTypeManager()
+	parser = DataTypeParser(dtm, dtm, None, AllowedDataTypes.ALL)
+	for overlay_id, addr, name, typ in VARS:
+		a = addr_of(overlay_id, addr)
+		createLabel(a, name, True, SourceType.IMPORTED)
+		try:
+			dt = parser.parse(typ)
+			clearListing(a, a.add(max(dt.getLength(), 1) - 1))
+			createData(a, dt)
+		except Exception as e:
+			print("unable to apply type %r at %s; %s" % (typ, a, e))
+
+def set_line_comments():
+	listing = currentProgram.getListing()
+	for overlay_id, addr, comment in LINES:
+		a = addr_of(overlay_id, addr)
+		# Append to present comments, unless already added.
+		prev = listing.getComment(CodeUnit.EOL_COMMENT, a)
+		if prev:
+			if comment in prev:
+				continue
+			comment = prev + "\n" + comment
+		listing.setComment(a, CodeUnit.EOL_COMMENT, comment)
+
+load_types()
+create_overlays()
+create_labels()
+create_funcs()
+create_vars()
+set_line_comments()
+`
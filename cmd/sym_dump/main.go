@@ -21,6 +21,20 @@ import (
 func usage() {
 	const use = `
 Convert Playstation 1 SYM files to C headers (*.sym -> *.h) and scripts for importing symbol information into IDA.
+
+Usage:
+
+	sym_dump [OPTION]... FILE.sym...
+	sym_dump [OPTION]... xref NAME FILE.sym...
+	sym_dump [OPTION]... context FUNC FILE.sym...
+
+Sub-commands:
+
+	xref       list the users (fields, globals, params, returns, locals) of the named type
+	context    print the types and declarations used by the named function, as a self-contained C header
+	           (the SYM does not record the global variables referenced by a function; globals of the
+	           struct, union and enum types used by the function are included, except for statics of
+	           other source files, and globals of scalar types are left out)
 `
 	fmt.Println(use[1:])
 	flag.PrintDefaults()
@@ -32,33 +46,77 @@ const dumpDir = "_dump_"
 func main() {
 	// Command line flags.
 	var (
-		// Output C types and declarations.
-		outputC bool
-		// Output directory.
-		outputDir string
-		// Output IDA scripts.
-		outputIDA bool
-		// Merge SYM files.
-		merge bool
-		// Split output into source files.
-		splitSrc bool
-		// Output C types.
-		outputTypes bool
+		// Output format and options.
+		opts dumpOptions
+		// Expand type definitions.
+		expand bool
+		// Output ELF file.
+		elfPath string
 	)
-	flag.BoolVar(&outputC, "c", false, "output C types and declarations")
-	flag.StringVar(&outputDir, "dir", dumpDir, "output directory")
-	flag.BoolVar(&outputIDA, "ida", false, "output IDA scripts")
-	flag.BoolVar(&merge, "merge", false, "merge SYM files")
-	flag.BoolVar(&splitSrc, "src", false, "split output into source files")
-	flag.BoolVar(&outputTypes, "types", false, "output C types")
+	flag.BoolVar(&opts.outputC, "c", false, "output C types and declarations")
+	flag.BoolVar(&opts.outputCPP, "cpp", false, "output C++ class declarations of C++ types")
+	flag.BoolVar(&opts.outputDecomp, "decomp", false, "output splat decompilation project scaffolding")
+	flag.StringVar(&opts.outputDir, "dir", dumpDir, "output directory")
+	flag.StringVar(&elfPath, "elf", "", "output ELF file with DWARF debug information")
+	flag.BoolVar(&opts.outputEmu, "emu", false, "output symbol maps of emulator debuggers (PCSX-Redux only; without sizes or source lines, see -elf)")
+	flag.StringVar(&opts.exePath, "exe", "", "PS-X EXE executable of code and data (used with -elf for .text and call frame information; required with -decomp)")
+	flag.BoolVar(&expand, "expand", false, "expand type definitions to their underlying types")
+	flag.BoolVar(&opts.outputGhidra, "ghidra", false, "output Ghidra script")
+	flag.BoolVar(&opts.outputIDA, "ida", false, "output IDA scripts")
+	flag.BoolVar(&opts.outputMap, "map", false, "output linker map of object modules and sections")
+	flag.BoolVar(&opts.merge, "merge", false, "merge SYM files")
+	flag.BoolVar(&opts.splitSrc, "src", false, "split output into source files")
+	flag.StringVar(&opts.tmplPath, "template", "", "output using text/template file (e.g. file.tmpl)")
+	flag.BoolVar(&opts.outputTypes, "types", false, "output C types")
 	flag.Usage = usage
 	flag.Parse()
-	if merge && outputIDA {
-		log.Fatalf("IDA output not supported in merge mode, as the scripts would be unusable.")
+	// Sub-commands.
+	if flag.Arg(0) == "xref" {
+		if flag.NArg() < 3 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		if err := xref(flag.Arg(1), flag.Args()[2:], expand); err != nil {
+			log.Fatalf("%+v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "context" {
+		if flag.NArg() < 3 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		if err := funcContext(flag.Arg(1), flag.Args()[2:], expand); err != nil {
+			log.Fatalf("%+v", err)
+		}
+		return
+	}
+	if formats := opts.formats(); len(formats) > 1 {
+		log.Fatalf("only one output format may be specified, got %s.", strings.Join(formats, " "))
+	}
+	if opts.merge && opts.outputGhidra {
+		log.Fatalf("Ghidra output not supported in merge mode, as the script would be unusable.")
+	}
+	if opts.merge && opts.outputMap {
+		log.Fatalf("linker map output not supported in merge mode, as the address spaces differ.")
+	}
+	if opts.outputDecomp && len(opts.exePath) == 0 {
+		log.Fatalf("decompilation project output requires the PS-X EXE executable (-exe), as file offsets are relative to its load address.")
+	}
+	if opts.merge && opts.outputDecomp {
+		log.Fatalf("decompilation project output not supported in merge mode, as the address spaces differ.")
+	}
+	if opts.merge && opts.outputEmu {
+		log.Fatalf("emulator symbol map output not supported in merge mode, as the address spaces differ.")
+	}
+	if opts.merge && len(elfPath) > 0 {
+		log.Fatalf("ELF output not supported in merge mode, as the address spaces differ.")
 	}
 
 	// Parse SYM files.
 	var ps []*csym.Parser
+	// Paths of merged SYM files.
+	var inputPaths []string
 	for _, path := range flag.Args() {
 		// Parse SYM file.
 		f, err := sym.ParseFile(path)
@@ -66,30 +124,43 @@ func main() {
 			log.Fatalf("%+v", err)
 		}
 		switch {
-		case outputC, outputIDA:
+		case opts.outputC, opts.outputIDA, opts.outputGhidra, opts.outputMap, opts.outputEmu, opts.outputDecomp, len(elfPath) > 0, len(opts.tmplPath) > 0:
 			// Parse C types and declarations.
 			p := csym.NewParser()
-			if merge {
+			p.ExpandTypedefs = expand
+			if opts.merge {
 				ps = append(ps, p)
+				inputPaths = append(inputPaths, path)
 			}
 			p.ParseTypes(f.Syms)
-			p.ParseDecls(f.Syms)
+			if err := p.ParseDecls(f.Syms); err != nil {
+				log.Fatalf("%+v", err)
+			}
+			p.NameFakeTags()
 			// Output once for each files if not in merge mode.
-			if !merge {
-				if err := dump(p, outputDir, outputC, outputTypes, outputIDA, splitSrc, merge); err != nil {
+			if !opts.merge {
+				if err := dump(p, &opts); err != nil {
 					log.Fatalf("%+v", err)
 				}
+				if len(elfPath) > 0 {
+					if err := dumpELF(p, elfPath, opts.exePath); err != nil {
+						log.Fatalf("%+v", err)
+					}
+				}
 			}
-		case outputTypes:
+		case opts.outputTypes:
 			// Parse C types.
 			p := csym.NewParser()
-			if merge {
+			p.ExpandTypedefs = expand
+			if opts.merge {
 				ps = append(ps, p)
+				inputPaths = append(inputPaths, path)
 			}
 			p.ParseTypes(f.Syms)
+			p.NameFakeTags()
 			// Output once for each files if not in merge mode.
-			if !merge {
-				if err := dump(p, outputDir, outputC, outputTypes, outputIDA, splitSrc, merge); err != nil {
+			if !opts.merge {
+				if err := dump(p, &opts); err != nil {
 					log.Fatalf("%+v", err)
 				}
 			}
@@ -100,117 +171,137 @@ func main() {
 		}
 	}
 	// Output the merge of all files if in merge mode.
-	if merge {
+	if opts.merge {
 		skipAddrDiff := true
 		skipLineDiff := true
-		p := pruneDuplicates(ps, skipAddrDiff, skipLineDiff)
-		if err := dump(p, outputDir, outputC, outputTypes, outputIDA, splitSrc, merge); err != nil {
+		p, prov := pruneDuplicates(ps, skipAddrDiff, skipLineDiff)
+		if err := dump(p, &opts); err != nil {
 			log.Fatalf("%+v", err)
 		}
+		// Output IDA scripts for each input file, referring to the merged
+		// types.
+		if opts.outputIDA {
+			if err := dumpMergeIDAScripts(p, ps, inputPaths, prov, opts.outputDir); err != nil {
+				log.Fatalf("%+v", err)
+			}
+		}
 	}
 }
 
 // pruneDuplicates prunes duplicates declarations of the parser, optionally
-// ignoring differences in address.
-func pruneDuplicates(ps []*csym.Parser, skipAddrDiff, skipLineDiff bool) *csym.Parser {
+// ignoring differences in address. The provenance of the unique declarations
+// is returned; i.e. their addresses in each overlay of the input files.
+func pruneDuplicates(ps []*csym.Parser, skipAddrDiff, skipLineDiff bool) (*csym.Parser, *provenance) {
 	dst := csym.NewParser()
-	enumPresent := make(map[string]bool)
-	structPresent := make(map[string]bool)
-	unionPresent := make(map[string]bool)
-	typeDefPresent := make(map[string]bool)
+	prov := newProvenance()
+	// canonical maps from duplicate type to its canonical type.
+	canonical := make(map[c.Type]c.Type)
 	// Add unique predeclared identifiers.
 	if def, ok := ps[0].Types["bool"]; ok {
 		dst.Types["bool"] = def
+		for _, p := range ps[1:] {
+			canonical[p.Types["bool"]] = def
+		}
 	}
 
-	// placeholder type name to make types match even when typename differ.
-	const placeholder = "placeholder"
-	fakeEnum := 0
-	fakeStruct := 0
-	fakeUnion := 0
+	// Locate unique types. Tags are renamed only after all types have been
+	// compared, as the tags of referenced types are part of type identity.
+	types := newTypeSet(c.Comparer{IgnoreFakeTags: true})
+	var enums, structs, unions, typedefs []origType
 	for pnum, p := range ps {
-		// Add unique enums.
 		for _, tag := range p.EnumTags {
 			t := p.Enums[tag]
-			fake := strings.Contains(t.Tag, "fake")
-			if fake {
-				t.Tag = placeholder
-			}
-			s := t.Def()
-			if fake {
-				tag = fmt.Sprintf("enum_fake_%d_%d", pnum, fakeEnum)
-				fakeEnum++
-				t.Tag = tag
-			}
-			if !enumPresent[s] {
-				if !fake {
-					if _, ok := dst.Enums[tag]; ok {
-						tag = fmt.Sprintf("%s_dup_%d", tag, pnum)
-						t.Tag = tag
-					}
-				}
-				dst.Enums[tag] = t
-				dst.EnumTags = append(dst.EnumTags, tag)
+			if u, ok := types.add(t); !ok {
+				canonical[t] = u
+				continue
 			}
-			enumPresent[s] = true
+			enums = append(enums, origType{pnum: pnum, t: t})
 		}
-		// Add unique structs.
 		for _, tag := range p.StructTags {
 			t := p.Structs[tag]
-			fake := strings.Contains(t.Tag, "fake")
-			if fake {
-				t.Tag = placeholder
-			}
-			s := t.Def()
-			if fake {
-				tag = fmt.Sprintf("struct_fake_%d_%d", pnum, fakeStruct)
-				fakeStruct++
-				t.Tag = tag
-			}
-			if !structPresent[s] {
-				if !fake {
-					if _, ok := dst.Structs[tag]; ok {
-						tag = fmt.Sprintf("%s_dup_%d", tag, pnum)
-						t.Tag = tag
-					}
-				}
-				dst.Structs[tag] = t
-				dst.StructTags = append(dst.StructTags, tag)
+			if u, ok := types.add(t); !ok {
+				canonical[t] = u
+				continue
 			}
-			structPresent[s] = true
+			structs = append(structs, origType{pnum: pnum, t: t})
 		}
-		// Add unique unions.
 		for _, tag := range p.UnionTags {
 			t := p.Unions[tag]
-			fake := strings.Contains(t.Tag, "fake")
-			if fake {
-				t.Tag = placeholder
-			}
-			s := t.Def()
-			if fake {
-				tag = fmt.Sprintf("union_fake_%d_%d", pnum, fakeUnion)
-				fakeUnion++
-				t.Tag = tag
-			}
-			if !unionPresent[s] {
-				if !fake {
-					if _, ok := dst.Unions[tag]; ok {
-						tag = fmt.Sprintf("%s_dup_%d", tag, pnum)
-						t.Tag = tag
-					}
-				}
-				dst.Unions[tag] = t
-				dst.UnionTags = append(dst.UnionTags, tag)
+			if u, ok := types.add(t); !ok {
+				canonical[t] = u
+				continue
 			}
-			unionPresent[s] = true
+			unions = append(unions, origType{pnum: pnum, t: t})
 		}
-		// Add unique typedefs.
 		for _, def := range p.Typedefs {
-			s := def.Def()
-			if _, ok := typeDefPresent[s]; !ok {
-				dst.Typedefs = append(dst.Typedefs, def)
+			if u, ok := types.add(def); !ok {
+				canonical[def] = u
+				continue
+			}
+			typedefs = append(typedefs, origType{pnum: pnum, t: def})
+		}
+	}
+
+	// Add unique enums.
+	for _, o := range enums {
+		t := o.t.(*c.EnumType)
+		tag := t.Tag
+		if dst.Enums[tag] != nil {
+			tag = fmt.Sprintf("%s_dup_%d", tag, o.pnum)
+		}
+		t.Tag = tag
+		dst.Enums[tag] = t
+		dst.EnumTags = append(dst.EnumTags, tag)
+	}
+	// Add unique structs.
+	for _, o := range structs {
+		t := o.t.(*c.StructType)
+		tag := t.Tag
+		if dst.Structs[tag] != nil {
+			tag = fmt.Sprintf("%s_dup_%d", tag, o.pnum)
+		}
+		t.Tag = tag
+		dst.Structs[tag] = t
+		dst.StructTags = append(dst.StructTags, tag)
+	}
+	// Add unique unions.
+	for _, o := range unions {
+		t := o.t.(*c.UnionType)
+		tag := t.Tag
+		if dst.Unions[tag] != nil {
+			tag = fmt.Sprintf("%s_dup_%d", tag, o.pnum)
+		}
+		t.Tag = tag
+		dst.Unions[tag] = t
+		dst.UnionTags = append(dst.UnionTags, tag)
+	}
+	// Add unique typedefs.
+	for _, o := range typedefs {
+		def := o.t.(*c.VarDecl)
+		dst.Typedefs = append(dst.Typedefs, def)
+		dst.Types[def.Name] = def
+	}
+
+	// Replace references to duplicate types with their canonical types.
+	r := c.NewRewriter(func(t c.Type) c.Type {
+		if u, ok := canonical[t]; ok {
+			return u
+		}
+		return t
+	})
+	for _, origs := range [][]origType{enums, structs, unions, typedefs} {
+		for _, o := range origs {
+			r.Rewrite(o.t)
+		}
+	}
+	for _, p := range ps {
+		for _, overlay := range append(p.Overlays, p.Overlay) {
+			for _, v := range overlay.Vars {
+				r.Rewrite(v)
+			}
+			for _, f := range overlay.Funcs {
+				r.Rewrite(f)
 			}
-			typeDefPresent[s] = true
 		}
 	}
 
@@ -225,10 +316,10 @@ func pruneDuplicates(ps []*csym.Parser, skipAddrDiff, skipLineDiff bool) *csym.P
 	}
 	sort.Slice(dst.Typedefs, less)
 
-	varDeclPresent := make(map[string]bool)
-	funcDeclPresent := make(map[string]bool)
+	varDeclPresent := make(map[string]*c.VarDecl)
+	funcDeclPresent := make(map[string]*c.FuncDecl)
 	dstOverlays := make(map[uint32]*csym.Overlay)
-	for _, p := range ps {
+	for pnum, p := range ps {
 		// Add unique declarations of each overlay.
 		overlays := append(p.Overlays, p.Overlay)
 		for _, overlay := range overlays {
@@ -249,18 +340,23 @@ func pruneDuplicates(ps []*csym.Parser, skipAddrDiff, skipLineDiff bool) *csym.P
 			}
 			// Add unique variable declarations.
 			for _, v := range overlay.Vars {
-				origAddr := v.Addr
+				origLoc := v.Loc
 				if skipAddrDiff {
-					v.Addr = 0
+					v.Loc = nil
 				}
 				s := v.Def()
 				if skipAddrDiff {
-					v.Addr = origAddr
+					v.Loc = origLoc
 				}
-				if !varDeclPresent[s] {
+				u, ok := varDeclPresent[s]
+				if !ok {
 					curOverlay.Vars = append(curOverlay.Vars, v)
+					varDeclPresent[s] = v
+					prov.varAddrs[v] = make(map[origin][]uint32)
+					u = v
 				}
-				varDeclPresent[s] = true
+				o := origin{pnum: pnum, overlayID: overlay.ID}
+				prov.varAddrs[u][o] = append(prov.varAddrs[u][o], v.Addr())
 			}
 			// Add unique function declarations.
 			for _, f := range overlay.Funcs {
@@ -282,10 +378,15 @@ func pruneDuplicates(ps []*csym.Parser, skipAddrDiff, skipLineDiff bool) *csym.P
 					f.LineStart = origLineStart
 					f.LineEnd = origLineEnd
 				}
-				if !funcDeclPresent[s] {
+				u, ok := funcDeclPresent[s]
+				if !ok {
 					curOverlay.Funcs = append(curOverlay.Funcs, f)
+					funcDeclPresent[s] = f
+					prov.funcAddrs[f] = make(map[origin][]uint32)
+					u = f
 				}
-				funcDeclPresent[s] = true
+				o := origin{pnum: pnum, overlayID: overlay.ID}
+				prov.funcAddrs[u][o] = append(prov.funcAddrs[u][o], f.Addr)
 			}
 		}
 	}
@@ -305,59 +406,246 @@ func pruneDuplicates(ps []*csym.Parser, skipAddrDiff, skipLineDiff bool) *csym.P
 		sort.Slice(overlay.Funcs, less)
 	}
 
+	return dst, prov
+}
+
+// origin identifies an overlay of an input file.
+type origin struct {
+	// Input file number.
+	pnum int
+	// Overlay ID.
+	overlayID uint32
+}
+
+// provenance records the addresses of merged declarations in each overlay of
+// the input files.
+type provenance struct {
+	// varAddrs maps from unique variable declaration to its addresses in each
+	// input overlay.
+	varAddrs map[*c.VarDecl]map[origin][]uint32
+	// funcAddrs maps from unique function declaration to its addresses in each
+	// input overlay.
+	funcAddrs map[*c.FuncDecl]map[origin][]uint32
+}
+
+// newProvenance returns a new provenance map of merged declarations.
+func newProvenance() *provenance {
+	return &provenance{
+		varAddrs:  make(map[*c.VarDecl]map[origin][]uint32),
+		funcAddrs: make(map[*c.FuncDecl]map[origin][]uint32),
+	}
+}
+
+// inputOverlay returns the given overlay of input file pnum, with the merged
+// declarations of the parser placed at their addresses in the input overlay.
+func (prov *provenance) inputOverlay(p *csym.Parser, pnum int, overlay *csym.Overlay) *csym.Overlay {
+	o := origin{pnum: pnum, overlayID: overlay.ID}
+	dst := &csym.Overlay{
+		Addr:    overlay.Addr,
+		ID:      overlay.ID,
+		Length:  overlay.Length,
+		Symbols: overlay.Symbols,
+		Lines:   overlay.Lines,
+	}
+	for _, merged := range append([]*csym.Overlay{p.Overlay}, p.Overlays...) {
+		for _, v := range merged.Vars {
+			for _, addr := range prov.varAddrs[v][o] {
+				w := *v
+				w.Loc = &c.StaticLoc{Addr: addr}
+				dst.Vars = append(dst.Vars, &w)
+			}
+		}
+		for _, f := range merged.Funcs {
+			for _, addr := range prov.funcAddrs[f][o] {
+				g := *f
+				g.Addr = addr
+				dst.Funcs = append(dst.Funcs, &g)
+			}
+		}
+	}
 	return dst
 }
 
-// dump dumps the declarations of the parser to the given output directory, in
-// the format specified.
-func dump(p *csym.Parser, outputDir string, outputC, outputTypes, outputIDA, splitSrc, merge bool) error {
+// origType is a type of a given input file.
+type origType struct {
+	// Input file number.
+	pnum int
+	// Type.
+	t c.Type
+}
+
+// typeSet is a set of structurally unique types.
+type typeSet struct {
+	// Type comparer.
+	cmp c.Comparer
+	// types maps from type hash to unique types with the given hash.
+	types map[uint64][]c.Type
+}
+
+// newTypeSet returns a new set of types unique according to cmp.
+func newTypeSet(cmp c.Comparer) *typeSet {
+	return &typeSet{
+		cmp:   cmp,
+		types: make(map[uint64][]c.Type),
+	}
+}
+
+// add adds the type to the set and reports whether it was added. If an
+// identical type is already present, it is returned instead.
+func (s *typeSet) add(t c.Type) (c.Type, bool) {
+	h := s.cmp.Hash(t)
+	for _, u := range s.types[h] {
+		if s.cmp.Equal(t, u) {
+			return u, false
+		}
+	}
+	s.types[h] = append(s.types[h], t)
+	return t, true
+}
+
+// dumpOptions specifies the output format and options of dump.
+type dumpOptions struct {
+	// Output directory.
+	outputDir string
+	// PS-X EXE executable of code and data.
+	exePath string
+	// Template file of custom output.
+	tmplPath string
+	// Output C types and declarations.
+	outputC bool
+	// Output C types.
+	outputTypes bool
+	// Output IDA scripts.
+	outputIDA bool
+	// Output Ghidra script.
+	outputGhidra bool
+	// Output linker map.
+	outputMap bool
+	// Output emulator symbol maps.
+	outputEmu bool
+	// Output decompilation project scaffolding.
+	outputDecomp bool
+	// Output C++ classes.
+	outputCPP bool
+	// Split output into source files.
+	splitSrc bool
+	// Merge SYM files.
+	merge bool
+}
+
+// formats returns the command line flags of the output formats specified.
+func (opts *dumpOptions) formats() []string {
+	var formats []string
+	for _, f := range []struct {
+		flag string
+		set  bool
+	}{
+		{flag: "-c", set: opts.outputC},
+		{flag: "-types", set: opts.outputTypes},
+		{flag: "-ida", set: opts.outputIDA},
+		{flag: "-ghidra", set: opts.outputGhidra},
+		{flag: "-map", set: opts.outputMap},
+		{flag: "-emu", set: opts.outputEmu},
+		{flag: "-decomp", set: opts.outputDecomp},
+		{flag: "-template", set: len(opts.tmplPath) > 0},
+	} {
+		if f.set {
+			formats = append(formats, f.flag)
+		}
+	}
+	return formats
+}
+
+// dump dumps the declarations of the parser to the output directory, in the
+// format specified.
+func dump(p *csym.Parser, opts *dumpOptions) error {
+	outputDir := opts.outputDir
 	switch {
-	case outputC:
+	case opts.outputC:
 		// Output C types and declarations.
 		if err := initOutputDir(outputDir); err != nil {
 			return errors.WithStack(err)
 		}
-		if err := dumpTypes(p, outputDir); err != nil {
-			return errors.WithStack(err)
-		}
-		if splitSrc {
-			if err := dumpSourceFiles(p, outputDir); err != nil {
+		if opts.splitSrc {
+			// Note, type definitions are split across source file headers.
+			if err := dumpSourceFiles(p, outputDir, opts.outputCPP); err != nil {
 				return errors.WithStack(err)
 			}
 		} else {
+			if err := dumpTypes(p, outputDir, opts.outputCPP); err != nil {
+				return errors.WithStack(err)
+			}
 			if err := dumpDecls(p, outputDir); err != nil {
 				return errors.WithStack(err)
 			}
 		}
-	case outputTypes:
+	case opts.outputTypes:
 		// Output C types.
 		if err := initOutputDir(outputDir); err != nil {
 			return errors.WithStack(err)
 		}
-		if err := dumpTypes(p, outputDir); err != nil {
+		if err := dumpTypes(p, outputDir, opts.outputCPP); err != nil {
 			return errors.WithStack(err)
 		}
-	case outputIDA:
-		// Output IDA scripts.
+	case opts.outputIDA:
+		// Output IDA scripts. In merge mode, only the type definitions are
+		// output here, as the declarations are output for each input file.
 		if err := initOutputDir(outputDir); err != nil {
 			return errors.WithStack(err)
 		}
-		if err := dumpIDAScripts(p, outputDir); err != nil {
+		if opts.merge {
+			if err := dumpIDATypes(p, outputDir); err != nil {
+				return errors.WithStack(err)
+			}
+		} else {
+			if err := dumpIDAScripts(p, outputDir); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if err := dumpTypes(p, outputDir, opts.outputCPP); err != nil {
 			return errors.WithStack(err)
 		}
-		// Delete bool and __int64 types as they cause issues with IDA.
-		delete(p.Types, "bool")
-		for i, def := range p.Typedefs {
-			if v, ok := def.(*c.VarDecl); ok {
-				if v.Name == "__int64" {
-					defs := append(p.Typedefs[:i], p.Typedefs[i+1:]...)
-					p.Typedefs = defs
-					break
-				}
-			}
+	case opts.outputGhidra:
+		// Output Ghidra script.
+		if err := initOutputDir(outputDir); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpGhidraScript(p, outputDir); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpTypes(p, outputDir, opts.outputCPP); err != nil {
+			return errors.WithStack(err)
+		}
+	case opts.outputMap:
+		// Output linker map.
+		if err := initOutputDir(outputDir); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpMap(p, outputDir); err != nil {
+			return errors.WithStack(err)
+		}
+	case opts.outputEmu:
+		// Output emulator symbol maps.
+		if err := initOutputDir(outputDir); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpEmuMaps(p, outputDir); err != nil {
+			return errors.WithStack(err)
+		}
+	case opts.outputDecomp:
+		// Output decompilation project scaffolding.
+		if err := initOutputDir(outputDir); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpDecomp(p, outputDir, opts.exePath); err != nil {
+			return errors.WithStack(err)
+		}
+	case len(opts.tmplPath) > 0:
+		// Output using template.
+		if err := initOutputDir(outputDir); err != nil {
+			return errors.WithStack(err)
 		}
-		delete(p.Types, "__int64")
-		if err := dumpTypes(p, outputDir); err != nil {
+		if err := dumpTemplate(p, outputDir, opts.tmplPath); err != nil {
 			return errors.WithStack(err)
 		}
 	}
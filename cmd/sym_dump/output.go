@@ -12,6 +12,7 @@ import (
 	"github.com/rickypai/natsort"
 	"github.com/sanctuary/sym/csym"
 	"github.com/sanctuary/sym/csym/c"
+	"github.com/sanctuary/sym/demangle"
 )
 
 // --- [ Type definitions ] ----------------------------------------------------
@@ -20,8 +21,8 @@ import (
 const typesName = "types.h"
 
 // dumpTypes outputs the type information recorded by the parser to a C header
-// stored in the output directory.
-func dumpTypes(p *csym.Parser, outputDir string) error {
+// stored in the output directory, optionally outputting C++ classes.
+func dumpTypes(p *csym.Parser, outputDir string, outputCPP bool) error {
 	// Create output file.
 	typesPath := filepath.Join(outputDir, typesName)
 	fmt.Println("creating:", typesPath)
@@ -36,30 +37,19 @@ func dumpTypes(p *csym.Parser, outputDir string) error {
 			return errors.WithStack(err)
 		}
 	}
-	// Print enums.
-	for _, tag := range p.EnumTags {
-		t := p.Enums[tag]
-		if _, err := fmt.Fprintf(f, "%s;\n\n", t.Def()); err != nil {
-			return errors.WithStack(err)
-		}
-	}
-	// Print structs.
-	for _, tag := range p.StructTags {
-		t := p.Structs[tag]
-		if _, err := fmt.Fprintf(f, "%s;\n\n", t.Def()); err != nil {
-			return errors.WithStack(err)
-		}
-	}
-	// Print unions.
-	for _, tag := range p.UnionTags {
-		t := p.Unions[tag]
-		if _, err := fmt.Fprintf(f, "%s;\n\n", t.Def()); err != nil {
-			return errors.WithStack(err)
+	// Print type definitions in dependency order.
+	return dumpTypeDefs(f, p.OrderedTypes(), outputCPP)
+}
+
+// dumpTypeDefs outputs the given type definitions, optionally outputting C++
+// classes, writing to w.
+func dumpTypeDefs(w io.Writer, ts []c.Type, outputCPP bool) error {
+	for _, t := range ts {
+		def := t.Def()
+		if t, ok := t.(*c.StructType); ok && outputCPP {
+			def = t.ClassDef()
 		}
-	}
-	// Print typedefs.
-	for _, def := range p.Typedefs {
-		if _, err := fmt.Fprintf(f, "%s;\n\n", def.Def()); err != nil {
+		if _, err := fmt.Fprintf(w, "%s;\n\n", def); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -143,72 +133,267 @@ type SourceFile struct {
 	vars []*c.VarDecl
 	// Function declarations.
 	funcs []*c.FuncDecl
+	// Type definitions used only by the source file, in dependency order.
+	types []c.Type
+	// Functions visible outside of the source file.
+	exported map[*c.FuncDecl]bool
 }
 
 // dumpSourceFiles outputs the source files recorded by the parser to the output
-// directory.
-func dumpSourceFiles(p *csym.Parser, outputDir string) error {
+// directory, each accompanied by a header of the type definitions used only by
+// the source file, prototypes of its non-static functions and extern
+// declarations of its non-static global variables. Type definitions shared by
+// several source files (or used by none) are output to a common header,
+// optionally outputting C++ classes.
+func dumpSourceFiles(p *csym.Parser, outputDir string, outputCPP bool) error {
 	srcs := getSourceFiles(p)
+	// Compute type footprint of source files.
+	owners := make(map[c.Type]*SourceFile)
+	shared := make(map[c.Type]bool)
 	for _, src := range srcs {
-		// Create source file directory.
-		path := strings.ToLower(src.Path)
-		path = strings.Replace(path, `\`, "/", -1)
-		if strings.HasPrefix(path[1:], ":/") {
-			path = path[len("c:/"):]
-		}
-		path = filepath.Join(outputDir, path)
-		dir := filepath.Dir(path)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return errors.WithStack(err)
+		var nodes []c.Node
+		for _, v := range src.vars {
+			nodes = append(nodes, v)
 		}
-		fmt.Println("creating:", path)
-		f, err := os.Create(path)
-		if err != nil {
-			return errors.WithStack(err)
+		for _, f := range src.funcs {
+			nodes = append(nodes, f)
 		}
-		defer f.Close()
-		if err := dumpSourceFile(f, src); err != nil {
+		src.types = p.ContextTypes(nodes...)
+		for _, t := range src.types {
+			if _, ok := t.(*c.TagDecl); ok {
+				continue
+			}
+			if owner, ok := owners[t]; ok && owner != src {
+				shared[t] = true
+			}
+			owners[t] = src
+		}
+	}
+	// isOwner reports whether the given type definition is used only by the
+	// given source file. Forward declarations belong to the source file of
+	// their underlying type.
+	isOwner := func(t c.Type, src *SourceFile) bool {
+		if d, ok := t.(*c.TagDecl); ok {
+			t = d.Type
+		}
+		return owners[t] == src && !shared[t]
+	}
+	isShared := func(t c.Type) bool {
+		if d, ok := t.(*c.TagDecl); ok {
+			t = d.Type
+		}
+		return owners[t] == nil || shared[t]
+	}
+	if err := dumpSharedTypes(p, outputDir, outputCPP, isShared); err != nil {
+		return errors.WithStack(err)
+	}
+	exported := p.ExportedFuncs()
+	for _, src := range srcs {
+		var types []c.Type
+		for _, t := range src.types {
+			if isOwner(t, src) {
+				types = append(types, t)
+			}
+		}
+		src.types = types
+		src.exported = exported
+		// Handle duplicate identifiers.
+		names := make(map[string]bool)
+		for _, v := range src.vars {
+			if names[v.Name] {
+				v.Name = csym.UniqueName(v.Name, v.Addr())
+			}
+			names[v.Name] = true
+		}
+		for _, f := range src.funcs {
+			if names[f.Name] {
+				f.Name = csym.UniqueName(f.Name, f.Addr)
+			}
+			names[f.Name] = true
+		}
+		if err := createSourceFile(src, outputDir, outputCPP); err != nil {
 			return errors.WithStack(err)
 		}
 	}
 	return nil
 }
 
-// dumpSourceFile outputs the declarations of the source file, writing to w.
-func dumpSourceFile(w io.Writer, src *SourceFile) error {
-	if _, err := fmt.Fprintf(w, "// %s\n\n", src.Path); err != nil {
+// createSourceFile creates the given source file and its header in the output
+// directory, optionally outputting C++ classes.
+func createSourceFile(src *SourceFile, outputDir string, outputCPP bool) error {
+	// Create source file directory.
+	relPath := sourceRelPath(src.Path)
+	path := filepath.Join(outputDir, relPath)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return errors.WithStack(err)
 	}
-	// Add types.h include directory.
-	if _, err := fmt.Fprintf(w, "#include %q\n\n", typesName); err != nil {
+	// Create source file header.
+	headerRelPath := sourceHeaderRelPath(relPath)
+	headerPath := filepath.Join(outputDir, headerRelPath)
+	typesRelPath, err := filepath.Rel(dir, filepath.Join(outputDir, typesName))
+	if err != nil {
 		return errors.WithStack(err)
 	}
-	// Handle duplicate identifiers.
-	names := make(map[string]bool)
-	for _, v := range src.vars {
-		if names[v.Name] {
-			v.Name = csym.UniqueName(v.Name, v.Addr)
+	fmt.Println("creating:", headerPath)
+	h, err := os.Create(headerPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create source file header %q", headerPath)
+	}
+	defer h.Close()
+	if err := dumpSourceHeader(h, src, headerRelPath, filepath.ToSlash(typesRelPath), outputCPP); err != nil {
+		return errors.WithStack(err)
+	}
+	// Create source file.
+	fmt.Println("creating:", path)
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if err := dumpSourceFile(f, src, filepath.Base(headerRelPath)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// dumpSharedTypes outputs the type definitions recorded by the parser which
+// satisfy the given predicate to the common C header stored in the output
+// directory, optionally outputting C++ classes.
+func dumpSharedTypes(p *csym.Parser, outputDir string, outputCPP bool, pred func(t c.Type) bool) error {
+	// Create output file.
+	typesPath := filepath.Join(outputDir, typesName)
+	fmt.Println("creating:", typesPath)
+	f, err := os.Create(typesPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	guard := headerGuard(typesName)
+	if _, err := fmt.Fprintf(f, "#ifndef %s\n#define %s\n\n", guard, guard); err != nil {
+		return errors.WithStack(err)
+	}
+	// Print predeclared identifiers.
+	if def, ok := p.Types["bool"]; ok {
+		if _, err := fmt.Fprintf(f, "%s;\n\n", def.Def()); err != nil {
+			return errors.WithStack(err)
 		}
-		names[v.Name] = true
 	}
-	for _, f := range src.funcs {
-		if names[f.Name] {
-			f.Name = csym.UniqueName(f.Name, f.Addr)
+	// Print type definitions in dependency order.
+	var types []c.Type
+	for _, t := range p.OrderedTypes() {
+		if pred(t) {
+			types = append(types, t)
 		}
-		names[f.Name] = true
 	}
-	// Print variable declarations.
+	if err := dumpTypeDefs(f, types, outputCPP); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintf(f, "#endif // %s\n", guard); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// sourceRelPath returns the output path of the given source path (DOS or UNIX),
+// relative to the output directory; e.g. "C:\PSX\SRC\MAIN.C" -> "psx/src/main.c".
+func sourceRelPath(path string) string {
+	path = strings.ToLower(path)
+	path = strings.Replace(path, `\`, "/", -1)
+	if strings.HasPrefix(path[1:], ":/") {
+		path = path[len("c:/"):]
+	}
+	return path
+}
+
+// sourceHeaderRelPath returns the output path of the header of the given
+// source file output path; e.g. "psx/src/main.c" -> "psx/src/main.h".
+func sourceHeaderRelPath(relPath string) string {
+	base := strings.TrimSuffix(relPath, filepath.Ext(relPath))
+	if headerPath := base + ".h"; headerPath != relPath {
+		return headerPath
+	}
+	// Functions defined in header files.
+	return base + "_decls.h"
+}
+
+// headerGuard returns the include guard macro name of the given header path;
+// e.g. "psx/src/main.h" -> "PSX_SRC_MAIN_H".
+func headerGuard(headerPath string) string {
+	name := strings.ToUpper(headerPath)
+	guard := strings.Map(func(r rune) rune {
+		if ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	// Identifiers may not start with a digit; e.g. "3d.h" -> "H_3D_H".
+	if len(guard) > 0 && '0' <= guard[0] && guard[0] <= '9' {
+		guard = "H_" + guard
+	}
+	return guard
+}
+
+// dumpSourceHeader outputs the type definitions and non-static declarations of
+// the source file, writing to w.
+func dumpSourceHeader(w io.Writer, src *SourceFile, headerPath, typesPath string, outputCPP bool) error {
+	guard := headerGuard(headerPath)
+	if _, err := fmt.Fprintf(w, "// %s\n\n#ifndef %s\n#define %s\n\n", src.Path, guard, guard); err != nil {
+		return errors.WithStack(err)
+	}
+	// Add common header include directive.
+	if _, err := fmt.Fprintf(w, "#include %q\n\n", typesPath); err != nil {
+		return errors.WithStack(err)
+	}
+	// Print type definitions in dependency order.
+	if err := dumpTypeDefs(w, src.types, outputCPP); err != nil {
+		return errors.WithStack(err)
+	}
+	// Print extern variable declarations.
 	for _, v := range src.vars {
-		if _, err := fmt.Fprintf(w, "%s;\n\n", v.Def()); err != nil {
+		if v.Class == c.Static {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "extern %s;\n", v.Var); err != nil {
 			return errors.WithStack(err)
 		}
 	}
-	// Print function declarations.
+	// Print function prototypes.
 	for _, f := range src.funcs {
-		if _, err := fmt.Fprintf(w, "%s\n\n", f.Def()); err != nil {
+		if !src.exported[f] {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s;\n", f.Var); err != nil {
 			return errors.WithStack(err)
 		}
 	}
+	if _, err := fmt.Fprintf(w, "\n#endif // %s\n", guard); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// dumpSourceFile outputs the declarations of the source file, writing to w.
+// Functions are output as skeleton definitions aligned with their original
+// line numbers.
+func dumpSourceFile(w io.Writer, src *SourceFile, headerName string) error {
+	lw := newLineWriter(w)
+	lw.printf("// %s\n\n", src.Path)
+	// Add source file header include directive.
+	lw.printf("#include %q\n\n", headerName)
+	// Print variable definitions. Note, the extern declarations of global
+	// variables are output to the source file header.
+	for _, v := range src.vars {
+		def := *v
+		if def.Class == c.Extern {
+			def.Class = 0
+		}
+		lw.printf("%s;\n\n", def.Def())
+	}
+	// Print function definitions.
+	if err := dumpSkeleton(lw, src.funcs); err != nil {
+		return errors.WithStack(err)
+	}
 	return nil
 }
 
@@ -217,6 +402,10 @@ func dumpSourceFile(w io.Writer, src *SourceFile) error {
 // dumpIDAScripts outputs the declarations recorded by the parser to IDA scripts
 // stored in the output directory.
 func dumpIDAScripts(p *csym.Parser, outputDir string) error {
+	// Create script for type definitions.
+	if err := dumpIDATypes(p, outputDir); err != nil {
+		return errors.WithStack(err)
+	}
 	// Create scripts for declarations of default binary.
 	if err := dumpIDAOverlay(p.Overlay, outputDir); err != nil {
 		return errors.WithStack(err)
@@ -230,16 +419,78 @@ func dumpIDAScripts(p *csym.Parser, outputDir string) error {
 	return nil
 }
 
+// dumpMergeIDAScripts outputs IDA scripts for each merged input file, stored in
+// a subdirectory of the output directory named after the input file. The
+// scripts place the merged declarations of the parser at their addresses in the
+// input file, and thus refer to the type definitions shared by all input files.
+func dumpMergeIDAScripts(p *csym.Parser, ps []*csym.Parser, inputPaths []string, prov *provenance, outputDir string) error {
+	dirNames := make(map[string]bool)
+	for pnum, input := range ps {
+		inputPath := inputPaths[pnum]
+		dirName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+		if dirNames[dirName] {
+			dirName = fmt.Sprintf("%s_%d", dirName, pnum)
+		}
+		dirNames[dirName] = true
+		dir := filepath.Join(outputDir, dirName)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.WithStack(err)
+		}
+		for _, overlay := range append([]*csym.Overlay{input.Overlay}, input.Overlays...) {
+			if err := dumpIDAOverlay(prov.inputOverlay(p, pnum, overlay), dir); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
 // IDA script names.
 const (
+	// Script adding type definitions to the type library.
+	idaTypesName = "make_types.py"
 	// Scripts mapping addresses to identifiers.
 	idaIdentsName = "make_psx.py"
 	// Scripts adding function signatures to identifiers.
 	idaFuncsName = "set_funcs.py"
 	// Scripts adding global variable types to identifiers.
 	idaVarsName = "set_vars.py"
+	// Scripts adding source line comments.
+	idaLinesName = "set_lines.py"
 )
 
+// idaBuiltinTypes specifies the type names predefined by IDA, which may not be
+// redefined.
+var idaBuiltinTypes = map[string]bool{
+	"bool":    true,
+	"__int64": true,
+}
+
+// dumpIDATypes outputs the type definitions recorded by the parser to an IDA
+// script, which adds them to the type library in dependency order, parsing all
+// type definitions at once.
+func dumpIDATypes(p *csym.Parser, outputDir string) error {
+	typesPath := filepath.Join(outputDir, idaTypesName)
+	fmt.Println("creating:", typesPath)
+	w, err := os.Create(typesPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create type definitions IDA script %q", typesPath)
+	}
+	defer w.Close()
+	buf := &strings.Builder{}
+	for _, t := range p.OrderedTypes() {
+		if v, ok := t.(*c.VarDecl); ok && idaBuiltinTypes[v.Name] {
+			continue
+		}
+		buf.WriteString(t.Def())
+		buf.WriteString(";\n")
+	}
+	if _, err := fmt.Fprintf(w, "parse_decls(%s, PT_SILENT)\n", pyString(buf.String())); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
 // dumpIDAOverlay outputs the declarations of the overlay to IDA scripts.
 func dumpIDAOverlay(overlay *csym.Overlay, outputDir string) error {
 	// Create scripts for mapping addresses to identifiers.
@@ -258,19 +509,32 @@ func dumpIDAOverlay(overlay *csym.Overlay, outputDir string) error {
 		return errors.Wrapf(err, "unable to create declarations IDA script %q", identsPath)
 	}
 	defer w.Close()
+	if err := dumpIDASegment(w, overlay); err != nil {
+		return errors.WithStack(err)
+	}
 	// track addresses already assigned an identifier.
 	addrs := make(map[uint32]bool)
 	for _, f := range overlay.Funcs {
 		addrs[f.Addr] = true
-		if _, err := fmt.Fprintf(w, "set_name(0x%08X, %q, SN_NOWARN)\n", f.Addr, f.Name); err != nil {
+		if _, err := fmt.Fprintf(w, "set_name(%s, %q, SN_NOWARN)\n", idaAddr(overlay, f.Addr), f.Name); err != nil {
 			return errors.WithStack(err)
 		}
+		if len(f.Demangled) > 0 {
+			if _, err := fmt.Fprintf(w, "set_cmt(%s, %q, 1)\n", idaAddr(overlay, f.Addr), f.Demangled); err != nil {
+				return errors.WithStack(err)
+			}
+		}
 	}
 	for _, v := range overlay.Vars {
-		addrs[v.Addr] = true
-		if _, err := fmt.Fprintf(w, "set_name(0x%08X, %q, SN_NOWARN)\n", v.Addr, v.Name); err != nil {
+		addrs[v.Addr()] = true
+		if _, err := fmt.Fprintf(w, "set_name(%s, %q, SN_NOWARN)\n", idaAddr(overlay, v.Addr()), v.Name); err != nil {
 			return errors.WithStack(err)
 		}
+		if len(v.Demangled) > 0 {
+			if _, err := fmt.Fprintf(w, "set_cmt(%s, %q, 1)\n", idaAddr(overlay, v.Addr()), v.Demangled); err != nil {
+				return errors.WithStack(err)
+			}
+		}
 	}
 	// add identifiers for which type information is unknown.
 loop:
@@ -288,7 +552,7 @@ loop:
 				strings.HasSuffix(sym.Name, "_obj"),
 				strings.HasSuffix(sym.Name, "_org"):
 				// add comment and skip
-				if _, err := fmt.Fprintf(w, "set_cmt(0x%08X, %q, 0)\n", sym.Addr, sym.Name); err != nil {
+				if _, err := fmt.Fprintf(w, "set_cmt(%s, %q, 0)\n", idaAddr(overlay, sym.Addr), sym.Name); err != nil {
 					return errors.WithStack(err)
 				}
 				continue loop
@@ -302,9 +566,14 @@ loop:
 			continue
 		}
 		addrs[sym.Addr] = true
-		if _, err := fmt.Fprintf(w, "set_name(0x%08X, %q, SN_NOWARN)\n", sym.Addr, sym.Name); err != nil {
+		if _, err := fmt.Fprintf(w, "set_name(%s, %q, SN_NOWARN)\n", idaAddr(overlay, sym.Addr), sym.Name); err != nil {
 			return errors.WithStack(err)
 		}
+		if demangled, err := demangle.Demangle(sym.Name); err == nil {
+			if _, err := fmt.Fprintf(w, "set_cmt(%s, %q, 1)\n", idaAddr(overlay, sym.Addr), demangled); err != nil {
+				return errors.WithStack(err)
+			}
+		}
 	}
 	// Create scripts for adding function signatures to identifiers.
 	funcsPath := filepath.Join(dir, idaFuncsName)
@@ -314,11 +583,20 @@ loop:
 		return errors.Wrapf(err, "unable to create function signatures IDA script %q", funcsPath)
 	}
 	defer w.Close()
+	if err := dumpIDASegment(w, overlay); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.WriteString(w, idaSetFrameVar[1:]); err != nil {
+		return errors.WithStack(err)
+	}
 	for _, f := range overlay.Funcs {
-		if _, err := fmt.Fprintf(w, "del_items(0x%08X)\n", f.Addr); err != nil {
+		if _, err := fmt.Fprintf(w, "del_items(%s)\n", idaAddr(overlay, f.Addr)); err != nil {
+			return errors.WithStack(err)
+		}
+		if _, err := fmt.Fprintf(w, "SetType(%s, %q)\n", idaAddr(overlay, f.Addr), f.Var); err != nil {
 			return errors.WithStack(err)
 		}
-		if _, err := fmt.Fprintf(w, "SetType(0x%08X, %q)\n", f.Addr, f.Var); err != nil {
+		if err := dumpIDAFrame(w, overlay, f); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -330,11 +608,214 @@ loop:
 		return errors.Wrapf(err, "unable to create global variables IDA script %q", varsPath)
 	}
 	defer w.Close()
+	if err := dumpIDASegment(w, overlay); err != nil {
+		return errors.WithStack(err)
+	}
 	for _, v := range overlay.Vars {
-		if _, err := fmt.Fprintf(w, "del_items(0x%08X)\n", v.Addr); err != nil {
+		if _, err := fmt.Fprintf(w, "del_items(%s)\n", idaAddr(overlay, v.Addr())); err != nil {
 			return errors.WithStack(err)
 		}
-		if _, err := fmt.Fprintf(w, "SetType(0x%08X, %q)\n", v.Addr, v.Var); err != nil {
+		if _, err := fmt.Fprintf(w, "SetType(%s, %q)\n", idaAddr(overlay, v.Addr()), v.Var); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	// Create scripts adding source line comments.
+	linesPath := filepath.Join(dir, idaLinesName)
+	fmt.Println("creating:", linesPath)
+	w, err = os.Create(linesPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create source line comments IDA script %q", linesPath)
+	}
+	defer w.Close()
+	if err := dumpIDASegment(w, overlay); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.WriteString(w, idaAddLineCmt[1:]); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, line := range overlay.Lines {
+		comment := fmt.Sprintf("%s:%d", line.Path, line.Line)
+		if _, err := fmt.Fprintf(w, "add_line_cmt(%s, %q)\n", idaAddr(overlay, line.Addr), comment); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// dumpIDAFrame outputs the stack-resident local variables and parameters of
+// the function of the overlay to IDA scripts.
+//
+// Note, the stack offsets of the SYM are relative to the frame pointer, which
+// points to the bottom of the stack frame after the function prologue (i.e.
+// $sp). As such, they coincide with the member offsets of the IDA frame
+// structure, the base of which is the bottom of the local variables.
+func dumpIDAFrame(w io.Writer, overlay *csym.Overlay, f *c.FuncDecl) error {
+	if f.Frame == nil {
+		return nil
+	}
+	for _, v := range f.Frame.Vars {
+		stackLoc, ok := v.Loc.(*c.StackLoc)
+		if !ok || stackLoc.Offset < 0 {
+			// Note, offsets below the frame pointer are outside of the frame
+			// structure.
+			continue
+		}
+		typ := strings.TrimSpace(c.Var{Type: v.Type}.String())
+		if _, err := fmt.Fprintf(w, "set_frame_var(%s, %d, %q, %q)\n", idaAddr(overlay, f.Addr), stackLoc.Offset, v.Name, typ); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// dumpIDASegment outputs the segment of the overlay to an IDA script, which
+// locates the segment, creating it if not yet present. Overlays share address
+// ranges, so each overlay gets a distinct segment, relocated past the existing
+// segments if its address range is already in use; the addresses of the script
+// are relative to the overlay segment (see idaAddr).
+func dumpIDASegment(w io.Writer, overlay *csym.Overlay) error {
+	if overlay.ID == 0 {
+		return nil
+	}
+	name := fmt.Sprintf("overlay_%x", overlay.ID)
+	if _, err := fmt.Fprintf(w, idaSegment[1:], name, overlay.Addr, overlay.Addr+overlay.Length, overlay.Addr); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// idaAddr returns the IDA script expression of the given address of the
+// overlay.
+func idaAddr(overlay *csym.Overlay, addr uint32) string {
+	if overlay.ID == 0 {
+		return fmt.Sprintf("0x%08X", addr)
+	}
+	return fmt.Sprintf("ov(0x%08X)", addr)
+}
+
+// idaSegment locates the segment of an overlay, creating it if not yet present;
+// parameterized by the segment name, start address and end address of the
+// overlay, and the start address again. ov maps overlay addresses to addresses
+// of the segment.
+const idaSegment = `
+import idautils
+
+def overlay_segment(name, start, end):
+	for ea in idautils.Segments():
+		if get_segm_name(ea) == name:
+			return ea
+	base = start
+	for ea in idautils.Segments():
+		if ea < end and get_segm_end(ea) > start:
+			base = max(get_segm_end(ea) for ea in idautils.Segments())
+			base = (base + 0xFFFF) & ~0xFFFF
+			break
+	add_segm_ex(base, base + end - start, 0, 1, saRelPara, scPub, ADDSEG_NOSREG)
+	set_segm_name(base, name)
+	set_segm_class(base, "CODE")
+	return base
+
+OVERLAY_BASE = overlay_segment("%s", 0x%08X, 0x%08X)
+
+def ov(ea):
+	return OVERLAY_BASE + ea - 0x%08X
+
+`
+
+// idaSetFrameVar defines a typed stack frame variable of a function, at the
+// given offset of the frame structure.
+const idaSetFrameVar = `
+def set_frame_var(func_ea, offset, name, typ):
+	frame = get_frame_id(func_ea)
+	if frame in (None, -1, BADADDR):
+		return
+	if get_member_id(frame, offset) in (-1, BADADDR):
+		add_struc_member(frame, name, offset, FF_BYTE | FF_DATA, -1, 1)
+	else:
+		set_member_name(frame, offset, name)
+	member = get_member_id(frame, offset)
+	if member not in (-1, BADADDR):
+		SetType(member, typ)
+
+`
+
+// idaAddLineCmt adds a source line comment, unless already present.
+const idaAddLineCmt = `
+def add_line_cmt(ea, line):
+	cmt = get_cmt(ea, 0)
+	if not cmt:
+		set_cmt(ea, line, 0)
+	elif line not in cmt.split("\n"):
+		set_cmt(ea, cmt + "\n" + line, 0)
+
+`
+
+// --- [ Linker map ] ----------------------------------------------------------
+
+// Linker map file name.
+const mapName = "sym.map"
+
+// dumpMap outputs the object modules and sections recorded by the parser as a
+// linker map.
+func dumpMap(p *csym.Parser, outputDir string) error {
+	mapPath := filepath.Join(outputDir, mapName)
+	fmt.Println("creating:", mapPath)
+	w, err := os.Create(mapPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create linker map %q", mapPath)
+	}
+	defer w.Close()
+	if err := dumpMapOverlay(w, p.Overlay); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, overlay := range p.Overlays {
+		if _, err := fmt.Fprintf(w, "\n=== [ Overlay ID %x (address 0x%08X, length 0x%X) ] ===\n\n", overlay.ID, overlay.Addr, overlay.Length); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := dumpMapOverlay(w, overlay); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// dumpMapOverlay outputs the object modules and sections of the overlay as a
+// linker map.
+func dumpMapOverlay(w io.Writer, overlay *csym.Overlay) error {
+	const format = "%-16s %-12s %-10s %-10s %-10s\n"
+	if _, err := fmt.Fprintf(w, format, "Module", "Section", "Start", "End", "Size"); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, module := range overlay.Modules() {
+		name := module.Name
+		if len(name) == 0 {
+			name = "-"
+		}
+		for _, sect := range module.Sections {
+			start := fmt.Sprintf("%08X", sect.Start())
+			end := fmt.Sprintf("%08X", sect.End())
+			size := fmt.Sprintf("%08X", sect.End()-sect.Start())
+			if sect.Size != 0 {
+				size = fmt.Sprintf("%08X", sect.Size)
+			}
+			if _, err := fmt.Fprintf(w, format, name, sect.Name, start, end, size); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	// Linker labels.
+	first := true
+	for _, s := range overlay.Symbols {
+		if !strings.HasPrefix(s.Name, "LNK_") {
+			continue
+		}
+		if first {
+			if _, err := fmt.Fprintf(w, "\n%-10s %s\n", "Address", "Linker label"); err != nil {
+				return errors.WithStack(err)
+			}
+			first = false
+		}
+		if _, err := fmt.Fprintf(w, "%08X   %s\n", s.Addr, s.Name); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -351,7 +832,10 @@ func getSourceFiles(p *csym.Parser) []*SourceFile {
 	sources := make(map[string]*SourceFile)
 	for _, overlay := range overlays {
 		for _, v := range overlay.Vars {
-			srcPath := fmt.Sprintf("global_%x.cpp", overlay.ID)
+			srcPath := v.Path
+			if len(srcPath) == 0 {
+				srcPath = fmt.Sprintf("global_%x.cpp", overlay.ID)
+			}
 			src, ok := sources[srcPath]
 			if !ok {
 				src = &SourceFile{
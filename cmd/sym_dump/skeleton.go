@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// --- [ Skeleton source files ] -----------------------------------------------
+
+// A lineWriter is a writer which tracks the current line number of the output,
+// to align declarations with their original line numbers.
+type lineWriter struct {
+	// Underlying writer.
+	w io.Writer
+	// Current line number (1-based).
+	line uint32
+	// First error encountered, if any.
+	err error
+}
+
+// newLineWriter returns a new line-tracking writer based on w.
+func newLineWriter(w io.Writer) *lineWriter {
+	return &lineWriter{w: w, line: 1}
+}
+
+// printf writes the formatted string, tracking line numbers.
+func (lw *lineWriter) printf(format string, args ...interface{}) {
+	if lw.err != nil {
+		return
+	}
+	s := fmt.Sprintf(format, args...)
+	if _, err := io.WriteString(lw.w, s); err != nil {
+		lw.err = errors.WithStack(err)
+		return
+	}
+	lw.line += uint32(strings.Count(s, "\n"))
+}
+
+// padTo pads the output with blank lines, so that the next line is written at
+// the given line number. If the output is already past the line number, a
+// #line directive is written instead when directive is set. Unknown (zero)
+// line numbers are ignored.
+func (lw *lineWriter) padTo(line uint32, directive bool) {
+	switch {
+	case line == 0 || line == lw.line:
+		// nothing to do.
+	case line > lw.line:
+		lw.printf("%s", strings.Repeat("\n", int(line-lw.line)))
+	case directive:
+		lw.printf("#line %d\n", line)
+		lw.line = line
+	}
+}
+
+// dumpSkeleton outputs skeleton definitions of the given functions, writing to
+// lw. Functions are output in line number order, each starting at its original
+// line number, with local variables declared in their lexical blocks at the
+// original block line numbers.
+func dumpSkeleton(lw *lineWriter, funcs []*c.FuncDecl) error {
+	funcs = append([]*c.FuncDecl(nil), funcs...)
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].LineStart < funcs[j].LineStart
+	})
+	for _, f := range funcs {
+		dumpSkeletonFunc(lw, f)
+		lw.printf("\n")
+	}
+	return lw.err
+}
+
+// dumpSkeletonFunc outputs a skeleton definition of the given function,
+// writing to lw.
+func dumpSkeletonFunc(lw *lineWriter, f *c.FuncDecl) {
+	// Comments are written before the function, if there is room for them.
+	comment := skeletonComment(f)
+	n := uint32(strings.Count(comment, "\n"))
+	if f.LineStart > lw.line+n {
+		lw.padTo(f.LineStart-n, false)
+	}
+	lw.printf("%s", comment)
+	lw.padTo(f.LineStart, true)
+	lw.printf("%s {\n", f.Var)
+	// The outermost block of a function with a single top-level block is the
+	// function body.
+	blocks := f.Blocks
+	if len(f.Blocks) == 1 {
+		body := f.Blocks[0]
+		dumpSkeletonLocals(lw, body, 1)
+		blocks = body.Blocks
+	}
+	lw.printf("\t// TODO\n")
+	for _, block := range blocks {
+		dumpSkeletonBlock(lw, block, 1)
+	}
+	lw.padTo(f.LineEnd, false)
+	lw.printf("}\n")
+}
+
+// dumpSkeletonBlock outputs the given block scope at the given indentation
+// level, writing to lw.
+func dumpSkeletonBlock(lw *lineWriter, block *c.Block, depth int) {
+	indent := strings.Repeat("\t", depth)
+	lw.padTo(block.LineStart, true)
+	lw.printf("%s{\n", indent)
+	dumpSkeletonLocals(lw, block, depth+1)
+	for _, child := range block.Blocks {
+		dumpSkeletonBlock(lw, child, depth+1)
+	}
+	lw.padTo(block.LineEnd, false)
+	lw.printf("%s}\n", indent)
+}
+
+// dumpSkeletonLocals outputs the local variables of the given block scope at
+// the given indentation level, one declaration per line, writing to lw.
+func dumpSkeletonLocals(lw *lineWriter, block *c.Block, depth int) {
+	indent := strings.Repeat("\t", depth)
+	for _, local := range block.Locals {
+		decl := local.Var.String()
+		if local.Class != 0 {
+			decl = fmt.Sprintf("%s %s", local.Class, decl)
+		}
+		switch loc := local.Loc.(type) {
+		case *c.RegLoc:
+			lw.printf("%s%s; // register: %s\n", indent, decl, loc)
+		case *c.StackLoc:
+			lw.printf("%s%s; // stack offset: %s\n", indent, decl, loc)
+		default:
+			lw.printf("%s%s;\n", indent, decl)
+		}
+	}
+}
+
+// skeletonComment returns the comments preceding the skeleton definition of
+// the given function; i.e. its address, size and stack frame layout.
+func skeletonComment(f *c.FuncDecl) string {
+	buf := &strings.Builder{}
+	if f.Addr > 0 {
+		fmt.Fprintf(buf, "// address: 0x%08X\n", f.Addr)
+	}
+	if f.Size > 0 {
+		fmt.Fprintf(buf, "// size: 0x%X\n", f.Size)
+	}
+	if len(f.Demangled) > 0 {
+		fmt.Fprintf(buf, "// demangled: %s\n", f.Demangled)
+	}
+	if f.Frame != nil {
+		buf.WriteString(f.Frame.Def())
+	}
+	return buf.String()
+}
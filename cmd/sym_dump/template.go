@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+	"github.com/sanctuary/sym/demangle"
+)
+
+// --- [ Template output ] -----------------------------------------------------
+
+// dumpTemplate outputs the declarations recorded by the parser using the given
+// text/template file, to a file of the output directory named after the
+// template file without its ".tmpl" extension.
+//
+// The parser is the data of the template; e.g. {{range .Funcs}} iterates over
+// the functions of the default binary and {{range .Overlays}} over overlays.
+// The helper functions of the template are listed in templateFuncs.
+func dumpTemplate(p *csym.Parser, outputDir, tmplPath string) error {
+	name := filepath.Base(tmplPath)
+	t, err := template.New(name).Funcs(templateFuncs).ParseFiles(tmplPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to parse template %q", tmplPath)
+	}
+	outName := strings.TrimSuffix(name, ".tmpl")
+	if outName == name {
+		outName += ".out"
+	}
+	outPath := filepath.Join(outputDir, outName)
+	fmt.Println("creating:", outPath)
+	f, err := os.Create(outPath)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create template output %q", outPath)
+	}
+	defer f.Close()
+	if err := t.Execute(f, p); err != nil {
+		return errors.Wrapf(err, "unable to execute template %q", tmplPath)
+	}
+	return nil
+}
+
+// templateFuncs maps from template function name to helper function.
+var templateFuncs = template.FuncMap{
+	// hex returns the hexadecimal representation of an integer, as an address;
+	// e.g. {{hex .Addr}} -> 0x80012345.
+	"hex": func(v interface{}) string {
+		return fmt.Sprintf("0x%08X", v)
+	},
+	// cdecl returns the C declaration of a variable or function, or the C
+	// definition of a type; e.g. {{cdecl .}} -> int DrawPlayer(int pnum).
+	"cdecl": cdecl,
+	// sizeof returns the size in bytes of a type or variable; e.g.
+	// {{sizeof .Type}}.
+	"sizeof": sizeof,
+	// demangle returns the demangled signature of a C++ symbol name, or the
+	// name itself if not mangled; e.g. {{demangle .Name}}.
+	"demangle": func(name string) string {
+		if demangled, err := demangle.Demangle(name); err == nil {
+			return demangled
+		}
+		return name
+	},
+	// sortBy returns a copy of the given slice, sorted by the named field or
+	// method of its elements; e.g. {{range sortBy "Addr" .Vars}}.
+	"sortBy": sortBy,
+}
+
+// cdecl returns the C declaration of the given variable or function, or the C
+// definition of the given type.
+func cdecl(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case *c.FuncDecl:
+		return v.Var.String(), nil
+	case *c.VarDecl:
+		if v.Class == 0 {
+			return v.Var.String(), nil
+		}
+		return fmt.Sprintf("%s %s", v.Class, v.Var), nil
+	case c.Var:
+		return v.String(), nil
+	case *c.Field:
+		return v.Var.String(), nil
+	case c.Field:
+		return v.Var.String(), nil
+	case c.Type:
+		return v.Def(), nil
+	default:
+		return "", errors.Errorf("support for C declaration of %T not yet implemented", v)
+	}
+}
+
+// sizeof returns the size in bytes of the given type or variable, or 0 if
+// unknown.
+func sizeof(v interface{}) (uint32, error) {
+	switch v := v.(type) {
+	case *c.VarDecl:
+		// Note, the size of type definitions is that of the underlying type.
+		if v.Size > 0 && v.Class != c.Typedef {
+			return v.Size, nil
+		}
+		return sizeof(v.Type)
+	case *c.FuncDecl:
+		return v.Size, nil
+	case c.Var:
+		return sizeof(v.Type)
+	case c.BaseType:
+		switch v {
+		case c.Void:
+			return 0, nil
+		case c.Char, c.UChar:
+			return 1, nil
+		case c.Short, c.UShort:
+			return 2, nil
+		default:
+			return 4, nil
+		}
+	case *c.StructType:
+		return v.Size, nil
+	case *c.UnionType:
+		return v.Size, nil
+	case *c.EnumType:
+		return v.Size, nil
+	case *c.PointerType, *c.FuncType:
+		return 4, nil
+	case *c.ArrayType:
+		elemSize, err := sizeof(v.Elem)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return uint32(v.Len) * elemSize, nil
+	case *c.TypedefType:
+		return sizeof(v.Type)
+	default:
+		return 0, errors.Errorf("support for size of %T not yet implemented", v)
+	}
+}
+
+// sortBy returns a copy of the given slice, stably sorted by the named field or
+// niladic method of its elements. Integer and string keys are supported.
+func sortBy(key string, list interface{}) (interface{}, error) {
+	src := reflect.ValueOf(list)
+	if src.Kind() != reflect.Slice {
+		return nil, errors.Errorf("invalid sortBy argument; expected slice, got %T", list)
+	}
+	dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+	reflect.Copy(dst, src)
+	keys := make([]reflect.Value, dst.Len())
+	for i := range keys {
+		k, err := sortKey(dst.Index(i), key)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		keys[i] = k
+	}
+	var err error
+	less := func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.String:
+			return a.String() < b.String()
+		default:
+			err = errors.Errorf("support for sort key of type %v not yet implemented", a.Type())
+			return false
+		}
+	}
+	// Note, keys are swapped alongside elements to keep them in sync.
+	swapElems := reflect.Swapper(dst.Interface())
+	sort.Stable(&keySorter{n: len(keys), less: less, swap: func(i, j int) {
+		swapElems(i, j)
+		keys[i], keys[j] = keys[j], keys[i]
+	}})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dst.Interface(), nil
+}
+
+// sortKey returns the named field or result of the named niladic method of the
+// given value.
+func sortKey(v reflect.Value, key string) (reflect.Value, error) {
+	if m := v.MethodByName(key); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+		return m.Call(nil)[0], nil
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, errors.Errorf("invalid sort key %q of nil value", key)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		if f := v.FieldByName(key); f.IsValid() {
+			return f, nil
+		}
+	}
+	return reflect.Value{}, errors.Errorf("unable to locate sort key %q of %v", key, v.Type())
+}
+
+// keySorter implements sort.Interface based on the given functions.
+type keySorter struct {
+	// Number of elements.
+	n int
+	// less reports whether the element with index i is less than the element
+	// with index j.
+	less func(i, j int) bool
+	// swap swaps the elements with indices i and j.
+	swap func(i, j int)
+}
+
+func (s *keySorter) Len() int           { return s.n }
+func (s *keySorter) Less(i, j int) bool { return s.less(i, j) }
+func (s *keySorter) Swap(i, j int)      { s.swap(i, j) }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym"
+	"github.com/sanctuary/sym/csym"
+)
+
+// xref prints the users of the named type (e.g. "RECT" or "struct RECT") of
+// the given SYM files.
+func xref(name string, symPaths []string, expand bool) error {
+	for _, symPath := range symPaths {
+		f, err := sym.ParseFile(symPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		p := csym.NewParser()
+		p.ExpandTypedefs = expand
+		p.ParseTypes(f.Syms)
+		if err := p.ParseDecls(f.Syms); err != nil {
+			return errors.WithStack(err)
+		}
+		p.NameFakeTags()
+		x := csym.NewXrefIndex(p)
+		xrefs := x.Lookup(name)
+		if len(symPaths) > 1 {
+			fmt.Printf("// %s\n", symPath)
+		}
+		if len(xrefs) == 0 {
+			fmt.Printf("no users of %q found\n", name)
+			continue
+		}
+		for _, xref := range xrefs {
+			embedded := ""
+			if xref.Embedded {
+				embedded = " (embedded)"
+			}
+			fmt.Printf("%-8s %-40s %s%s\n", xref.Kind, xref.Name, xref.Type, embedded)
+		}
+	}
+	return nil
+}
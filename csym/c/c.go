@@ -8,13 +8,22 @@ import (
 
 // A VarDecl is a variable declaration.
 type VarDecl struct {
-	// Address, frame pointer delta, or register depending on storage class
-	// (optional).
-	Addr uint32
+	// Storage location (optional).
+	Loc Location
 	// Size (optional).
 	Size uint32
 	// Storage class.
 	Class StorageClass
+	// Linkage name of global variables, if different from their name
+	// (optional); e.g. the mangled name of C++ static data members.
+	LinkageName string
+	// Demangled name of C++ static data members (optional); e.g.
+	// "CList::count".
+	Demangled string
+	// Source file (optional); inferred for global variables.
+	Path string
+	// Confidence of the inferred source file, between 0 and 1 (optional).
+	PathConfidence float64
 	// Underlying variable.
 	Var
 }
@@ -24,21 +33,38 @@ func (v *VarDecl) String() string {
 	return v.Name
 }
 
+// Addr returns the address of the variable declaration, or 0 if not of static
+// storage.
+func (v *VarDecl) Addr() uint32 {
+	if loc, ok := v.Loc.(*StaticLoc); ok {
+		return loc.Addr
+	}
+	return 0
+}
+
 // Def returns the C syntax representation of the definition of the variable
 // declaration.
 func (v *VarDecl) Def() string {
 	buf := &strings.Builder{}
-	switch v.Class {
-	case Register:
-		fmt.Fprintf(buf, "// register: %d\n", v.Addr)
-	default:
-		if v.Addr > 0 {
-			fmt.Fprintf(buf, "// address: 0x%08X\n", v.Addr)
+	switch loc := v.Loc.(type) {
+	case *RegLoc:
+		fmt.Fprintf(buf, "// register: %s\n", loc)
+	case *StackLoc:
+		fmt.Fprintf(buf, "// stack offset: %s\n", loc)
+	case *StaticLoc:
+		if loc.Addr > 0 {
+			fmt.Fprintf(buf, "// address: %s\n", loc)
 		}
 	}
 	if v.Size > 0 {
 		fmt.Fprintf(buf, "// size: 0x%X\n", v.Size)
 	}
+	if len(v.Demangled) > 0 {
+		fmt.Fprintf(buf, "// demangled: %s\n", v.Demangled)
+	}
+	if len(v.Path) > 0 {
+		fmt.Fprintf(buf, "// file: %s (confidence: %.1f)\n", v.Path, v.PathConfidence)
+	}
 	if v.Class == 0 {
 		fmt.Fprintf(buf, "%s", v.Var)
 	} else {
@@ -73,8 +99,16 @@ type FuncDecl struct {
 	LineStart uint32
 	// End line number.
 	LineEnd uint32
+	// Linkage name, if different from the function name (optional); e.g. the
+	// mangled name of C++ functions.
+	LinkageName string
+	// Demangled signature of C++ functions (optional); e.g.
+	// "CList::Add(int, char *)".
+	Demangled string
 	// Underlying function variable.
 	Var
+	// Stack frame layout (optional).
+	Frame *Frame
 	// Scope blocks.
 	Blocks []*Block
 }
@@ -95,6 +129,12 @@ func (f *FuncDecl) Def() string {
 	if f.Size > 0 {
 		fmt.Fprintf(buf, "// size: 0x%X\n", f.Size)
 	}
+	if len(f.Demangled) > 0 {
+		fmt.Fprintf(buf, "// demangled: %s\n", f.Demangled)
+	}
+	if f.Frame != nil {
+		buf.WriteString(f.Frame.Def())
+	}
 	fmt.Fprintf(buf, "// line start: %d\n", f.LineStart)
 	fmt.Fprintf(buf, "// line end:   %d\n", f.LineEnd)
 	if len(f.Blocks) == 0 {
@@ -102,28 +142,81 @@ func (f *FuncDecl) Def() string {
 		return buf.String()
 	}
 	fmt.Fprintf(buf, "%s ", f.Var)
-	for i, block := range f.Blocks {
-		indent := strings.Repeat("\t", i)
-		fmt.Fprintf(buf, "%s{\n", indent)
-		for _, local := range block.Locals {
-			indent := strings.Repeat("\t", i+1)
-			l := strings.Replace(local.Def(), "\n", "\n"+indent, -1)
-			fmt.Fprintf(buf, "%s%s;\n", indent, l)
-		}
+	if len(f.Blocks) == 1 {
+		f.Blocks[0].writeDef(buf, 0)
+		return buf.String()
 	}
-	for i := len(f.Blocks) - 1; i >= 0; i-- {
-		indent := strings.Repeat("\t", i)
-		fmt.Fprintf(buf, "%s}\n", indent)
+	buf.WriteString("{\n")
+	for _, block := range f.Blocks {
+		block.writeDef(buf, 1)
 	}
+	buf.WriteString("}\n")
 	return buf.String()
 }
 
+// AllBlocks returns the scope blocks of the function declaration, including
+// nested blocks, in pre-order.
+func (f *FuncDecl) AllBlocks() []*Block {
+	var blocks []*Block
+	var walk func(bs []*Block)
+	walk = func(bs []*Block) {
+		for _, block := range bs {
+			blocks = append(blocks, block)
+			walk(block.Blocks)
+		}
+	}
+	walk(f.Blocks)
+	return blocks
+}
+
 // A Block encapsulates a block scope.
 type Block struct {
-	// Start line number (relative to the function).
+	// Start address.
+	Addr uint32
+	// End address.
+	EndAddr uint32
+	// Start line number.
 	LineStart uint32
-	// End line number (relative to the function).
+	// End line number.
 	LineEnd uint32
 	// Local variables.
 	Locals []*VarDecl
+	// Nested blocks.
+	Blocks []*Block
+}
+
+// writeDef writes the C syntax representation of the block scope, at the
+// given indentation level.
+func (block *Block) writeDef(buf *strings.Builder, depth int) {
+	// The opening brace of the outermost block follows the function
+	// declaration.
+	if depth > 0 {
+		buf.WriteString(strings.Repeat("\t", depth))
+	}
+	buf.WriteString("{\n")
+	indent := strings.Repeat("\t", depth+1)
+	for _, local := range block.Locals {
+		l := strings.Replace(local.Def(), "\n", "\n"+indent, -1)
+		fmt.Fprintf(buf, "%s%s;\n", indent, l)
+	}
+	for _, child := range block.Blocks {
+		child.writeDef(buf, depth+1)
+	}
+	fmt.Fprintf(buf, "%s}\n", strings.Repeat("\t", depth))
+}
+
+// A TagDecl is a forward declaration of a struct or union tag.
+type TagDecl struct {
+	// Underlying struct or union type.
+	Type Type
+}
+
+// String returns the string representation of the tag declaration.
+func (d *TagDecl) String() string {
+	return d.Type.String()
+}
+
+// Def returns the C syntax representation of the tag declaration.
+func (d *TagDecl) Def() string {
+	return d.Type.String()
 }
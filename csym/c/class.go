@@ -0,0 +1,149 @@
+package c
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Class holds the C++ class information of a structure type.
+type Class struct {
+	// Base class subobjects.
+	Bases []*BaseClass
+	// Virtual table pointer field (optional).
+	VtblPtr *Field
+	// Member functions.
+	Methods []*Method
+	// Static data members.
+	Statics []*StaticMember
+}
+
+// A BaseClass is a base class subobject of a C++ class.
+type BaseClass struct {
+	// Offset of base class subobject.
+	Offset uint32
+	// Base class.
+	Type *StructType
+	// Index of the base class subobject field in the fields of the class.
+	Index int
+}
+
+// A Method is a member function of a C++ class.
+type Method struct {
+	// Method name; constructors are named after the class and destructors are
+	// prefixed with "~".
+	Name string
+	// Method type (optional).
+	Type *FuncType
+	// Function declaration (optional).
+	Func *FuncDecl
+}
+
+// decl returns the string representation of the method declaration in the
+// given class.
+func (m *Method) decl(tag string) string {
+	t := m.Type
+	if t == nil {
+		t = &FuncType{RetType: Int}
+	}
+	// Omit implicit this parameter.
+	params := t.Params
+	if len(params) > 0 && params[0].Name == "this" {
+		params = params[1:]
+	}
+	if m.Name != tag && m.Name != "~"+tag {
+		ft := &FuncType{
+			RetType:  t.RetType,
+			Params:   params,
+			Variadic: t.Variadic,
+		}
+		v := Var{Type: ft, Name: m.Name}
+		return v.String()
+	}
+	// Constructors and destructors lack return type.
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%s(", m.Name)
+	for i, param := range params {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(param.Var.String())
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// A StaticMember is a static data member of a C++ class.
+type StaticMember struct {
+	// Member name.
+	Name string
+	// Member type.
+	Type Type
+	// Variable declaration (optional).
+	Var *VarDecl
+}
+
+// String returns the string representation of the static member declaration.
+func (m *StaticMember) String() string {
+	v := Var{Type: m.Type, Name: m.Name}
+	return fmt.Sprintf("static %s", v)
+}
+
+// ClassDef returns the C++ syntax representation of the definition of the
+// structure type as a class. Structure types lacking class information are
+// output as plain C structures.
+func (t *StructType) ClassDef() string {
+	if t.Class == nil {
+		return t.Def()
+	}
+	buf := &strings.Builder{}
+	if t.Size > 0 {
+		fmt.Fprintf(buf, "// size: 0x%X\n", t.Size)
+	}
+	fmt.Fprintf(buf, "class %s", t.Tag)
+	for i, base := range t.Class.Bases {
+		if i == 0 {
+			buf.WriteString(" : ")
+		} else {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "public %s", base.Type.Tag)
+	}
+	buf.WriteString(" {\npublic:\n")
+	for i := range t.Fields {
+		if t.Class.isBase(i) {
+			continue
+		}
+		field := &t.Fields[i]
+		if field.Size > 0 {
+			fmt.Fprintf(buf, "\t// offset: %04X (%d bytes)\n", field.Offset, field.Size)
+		}
+		if field == t.Class.VtblPtr {
+			// The virtual table pointer is implicitly added by the compiler.
+			fmt.Fprintf(buf, "\t// virtual table pointer: %s;\n", field)
+			continue
+		}
+		fmt.Fprintf(buf, "\t%s;\n", field)
+	}
+	for _, m := range t.Class.Statics {
+		fmt.Fprintf(buf, "\t%s;\n", m)
+	}
+	for _, m := range t.Class.Methods {
+		if m.Func != nil && m.Func.Addr != 0 {
+			fmt.Fprintf(buf, "\t// address: 0x%08X\n", m.Func.Addr)
+		}
+		fmt.Fprintf(buf, "\t%s;\n", m.decl(t.Tag))
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// isBase reports whether the field with the given index is a base class
+// subobject.
+func (c *Class) isBase(index int) bool {
+	for _, base := range c.Bases {
+		if base.Index == index {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package c
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Frame is the stack frame layout of a function.
+type Frame struct {
+	// Frame pointer register.
+	FP Reg
+	// Frame size in bytes.
+	Size uint32
+	// Return address register.
+	RetReg Reg
+	// Saved general purpose registers.
+	Saved []*SavedReg
+	// Stack-resident local variables and parameters.
+	Vars []*VarDecl
+}
+
+// A SavedReg is a general purpose register saved in the stack frame.
+type SavedReg struct {
+	// Saved register.
+	Reg Reg
+	// Offset of stack slot, relative to the frame pointer.
+	Offset int32
+}
+
+// Def returns the frame layout of the stack frame, as a C comment.
+func (frame *Frame) Def() string {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "// frame pointer: %s\n", frame.FP)
+	fmt.Fprintf(buf, "// frame size: 0x%X\n", frame.Size)
+	fmt.Fprintf(buf, "// return address: %s\n", frame.RetReg)
+	type slot struct {
+		offset int32
+		desc   string
+	}
+	var slots []slot
+	for _, saved := range frame.Saved {
+		s := slot{offset: saved.Offset, desc: "saved " + saved.Reg.String()}
+		slots = append(slots, s)
+	}
+	for _, v := range frame.Vars {
+		loc, ok := v.Loc.(*StackLoc)
+		if !ok {
+			continue
+		}
+		s := slot{offset: loc.Offset, desc: v.Var.String()}
+		slots = append(slots, s)
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].offset < slots[j].offset
+	})
+	if len(slots) > 0 {
+		buf.WriteString("// frame layout:\n")
+	}
+	for _, s := range slots {
+		fmt.Fprintf(buf, "//    %s  %s\n", frameOffset(s.offset), s.desc)
+	}
+	return buf.String()
+}
+
+// frameOffset returns the string representation of the given frame offset.
+func frameOffset(offset int32) string {
+	if offset < 0 {
+		return fmt.Sprintf("-0x%04X", -int64(offset))
+	}
+	return fmt.Sprintf("+0x%04X", offset)
+}
@@ -0,0 +1,338 @@
+package c
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+)
+
+// A Comparer compares types for structural identity.
+type Comparer struct {
+	// Ignore the tags of structs, unions and enums.
+	IgnoreTags bool
+	// Ignore the compiler-generated tags of anonymous structs, unions and enums
+	// (e.g. "_12fake"). A fake tag is never identical to a real tag.
+	IgnoreFakeTags bool
+}
+
+// Equal reports whether the given types are structurally identical.
+func Equal(a, b Type) bool {
+	return Comparer{}.Equal(a, b)
+}
+
+// Hash returns a hash of the given type, such that structurally identical types
+// have the same hash.
+func Hash(t Type) uint64 {
+	return Comparer{}.Hash(t)
+}
+
+// Equal reports whether the given types are structurally identical.
+func (cmp Comparer) Equal(a, b Type) bool {
+	e := &equaler{
+		cmp:     cmp,
+		assumed: make(map[[2]Type]bool),
+	}
+	return e.equal(a, b)
+}
+
+// Hash returns a hash of the given type, such that structurally identical types
+// (as reported by cmp.Equal) have the same hash.
+func (cmp Comparer) Hash(t Type) uint64 {
+	h := &hasher{
+		cmp:    cmp,
+		Hash64: fnv.New64a(),
+	}
+	h.hash(t, false)
+	return h.Sum64()
+}
+
+// sameTag reports whether the given struct, union or enum tags are considered
+// identical.
+func (cmp Comparer) sameTag(a, b string) bool {
+	if cmp.IgnoreTags {
+		return true
+	}
+	if cmp.IgnoreFakeTags && IsFakeTag(a) && IsFakeTag(b) {
+		return true
+	}
+	return a == b
+}
+
+// --- [ Equality ] ------------------------------------------------------------
+
+// equaler tracks the state of a structural type comparison.
+type equaler struct {
+	cmp Comparer
+	// assumed records pairs of structs and unions assumed to be identical while
+	// being compared, thus handling recursive types.
+	assumed map[[2]Type]bool
+}
+
+// equal reports whether the given types are structurally identical.
+func (e *equaler) equal(a, b Type) bool {
+	if a == b {
+		return true
+	}
+	switch a := a.(type) {
+	case BaseType:
+		b, ok := b.(BaseType)
+		return ok && a == b
+	case *StructType:
+		b, ok := b.(*StructType)
+		if !ok || a.Size != b.Size || !e.cmp.sameTag(a.Tag, b.Tag) {
+			return false
+		}
+		if e.assume(a, b) {
+			return true
+		}
+		return e.equalFields(a.Fields, b.Fields) && e.equalClass(a.Class, b.Class)
+	case *UnionType:
+		b, ok := b.(*UnionType)
+		if !ok || a.Size != b.Size || !e.cmp.sameTag(a.Tag, b.Tag) {
+			return false
+		}
+		if e.assume(a, b) {
+			return true
+		}
+		return e.equalFields(a.Fields, b.Fields)
+	case *EnumType:
+		b, ok := b.(*EnumType)
+		if !ok || !e.cmp.sameTag(a.Tag, b.Tag) || len(a.Members) != len(b.Members) {
+			return false
+		}
+		am, bm := a.SortedMembers(), b.SortedMembers()
+		for i := range am {
+			if am[i].Name != bm[i].Name || am[i].Value != bm[i].Value {
+				return false
+			}
+		}
+		return true
+	case *PointerType:
+		b, ok := b.(*PointerType)
+		return ok && e.equal(a.Elem, b.Elem)
+	case *ArrayType:
+		b, ok := b.(*ArrayType)
+		return ok && a.Len == b.Len && e.equal(a.Elem, b.Elem)
+	case *FuncType:
+		b, ok := b.(*FuncType)
+		if !ok || a.Variadic != b.Variadic || a.Prototyped != b.Prototyped || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !e.equal(a.Params[i].Type, b.Params[i].Type) {
+				return false
+			}
+		}
+		return e.equal(a.RetType, b.RetType)
+	case *VarDecl:
+		// Type definition.
+		b, ok := b.(*VarDecl)
+		return ok && a.Class == b.Class && a.Name == b.Name && e.equal(a.Type, b.Type)
+	case *TypedefType:
+		b, ok := b.(*TypedefType)
+		return ok && a.Name == b.Name && e.equal(a.Type, b.Type)
+	case *TagDecl:
+		b, ok := b.(*TagDecl)
+		return ok && e.equal(a.Type, b.Type)
+	default:
+		return false
+	}
+}
+
+// assume records that the given structs or unions are assumed to be identical,
+// and reports whether they were already assumed to be so.
+func (e *equaler) assume(a, b Type) bool {
+	key := [2]Type{a, b}
+	if e.assumed[key] {
+		return true
+	}
+	e.assumed[key] = true
+	return false
+}
+
+// equalFields reports whether the given struct or union fields are
+// structurally identical.
+func (e *equaler) equalFields(a, b []Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Offset != b[i].Offset || a[i].Size != b[i].Size || a[i].Name != b[i].Name {
+			return false
+		}
+		if !e.equal(a[i].Type, b[i].Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalClass reports whether the given C++ class information is structurally
+// identical.
+func (e *equaler) equalClass(a, b *Class) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if (a.VtblPtr == nil) != (b.VtblPtr == nil) || len(a.Bases) != len(b.Bases) || len(a.Methods) != len(b.Methods) || len(a.Statics) != len(b.Statics) {
+		return false
+	}
+	if a.VtblPtr != nil && a.VtblPtr.Offset != b.VtblPtr.Offset {
+		return false
+	}
+	for i := range a.Bases {
+		if a.Bases[i].Offset != b.Bases[i].Offset || !e.equal(a.Bases[i].Type, b.Bases[i].Type) {
+			return false
+		}
+	}
+	for i := range a.Methods {
+		am, bm := a.Methods[i], b.Methods[i]
+		if am.Name != bm.Name || (am.Type == nil) != (bm.Type == nil) {
+			return false
+		}
+		if am.Type != nil && !e.equal(am.Type, bm.Type) {
+			return false
+		}
+	}
+	for i := range a.Statics {
+		if a.Statics[i].Name != b.Statics[i].Name || !e.equal(a.Statics[i].Type, b.Statics[i].Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// --- [ Hashing ] -------------------------------------------------------------
+
+// Type kinds used for hashing.
+const (
+	hashBase uint32 = iota + 1
+	hashStruct
+	hashUnion
+	hashEnum
+	hashPointer
+	hashArray
+	hashFunc
+	hashTypedef
+	hashTypedefRef
+	hashTagDecl
+)
+
+// hasher tracks the state of a structural type hash.
+type hasher struct {
+	cmp Comparer
+	hash.Hash64
+}
+
+// hash writes the structure of the given type to the hash. Shallow hashes of
+// structs and unions only include their tag and size, and are used for types
+// referred to through pointers, thus handling recursive types.
+func (h *hasher) hash(t Type, shallow bool) {
+	switch t := t.(type) {
+	case BaseType:
+		h.write(hashBase, uint32(t))
+	case *StructType:
+		h.write(hashStruct, t.Size)
+		h.tag(t.Tag)
+		if shallow {
+			return
+		}
+		h.fields(t.Fields)
+		h.class(t.Class)
+	case *UnionType:
+		h.write(hashUnion, t.Size)
+		h.tag(t.Tag)
+		if shallow {
+			return
+		}
+		h.fields(t.Fields)
+	case *EnumType:
+		h.write(hashEnum, uint32(len(t.Members)))
+		h.tag(t.Tag)
+		for _, member := range t.SortedMembers() {
+			h.write(hashEnum, uint32(member.Value))
+			h.str(member.Name)
+		}
+	case *PointerType:
+		h.write(hashPointer)
+		h.hash(t.Elem, true)
+	case *ArrayType:
+		h.write(hashArray, uint32(t.Len))
+		h.hash(t.Elem, shallow)
+	case *FuncType:
+		var variadic, prototyped uint32
+		if t.Variadic {
+			variadic = 1
+		}
+		if t.Prototyped {
+			prototyped = 1
+		}
+		h.write(hashFunc, variadic, prototyped)
+		h.hash(t.RetType, true)
+		for _, param := range t.Params {
+			h.hash(param.Type, true)
+		}
+	case *VarDecl:
+		h.write(hashTypedef, uint32(t.Class))
+		h.str(t.Name)
+		h.hash(t.Type, shallow)
+	case *TypedefType:
+		h.write(hashTypedefRef)
+		h.str(t.Name)
+		h.hash(t.Type, shallow)
+	case *TagDecl:
+		h.write(hashTagDecl)
+		h.hash(t.Type, true)
+	}
+}
+
+// fields writes the structure of the given struct or union fields to the hash.
+func (h *hasher) fields(fields []Field) {
+	for _, field := range fields {
+		h.write(field.Offset, field.Size)
+		h.str(field.Name)
+		h.hash(field.Type, false)
+	}
+	h.write(uint32(len(fields)))
+}
+
+// class writes the member names of the given C++ class information to the
+// hash.
+func (h *hasher) class(c *Class) {
+	if c == nil {
+		return
+	}
+	h.write(uint32(len(c.Bases)), uint32(len(c.Methods)), uint32(len(c.Statics)))
+	for _, m := range c.Methods {
+		h.str(m.Name)
+	}
+	for _, m := range c.Statics {
+		h.str(m.Name)
+	}
+}
+
+// tag writes the given struct, union or enum tag to the hash.
+func (h *hasher) tag(tag string) {
+	switch {
+	case h.cmp.IgnoreTags:
+		// nothing to do.
+	case h.cmp.IgnoreFakeTags && IsFakeTag(tag):
+		h.str("<fake>")
+	default:
+		h.str(tag)
+	}
+}
+
+// write writes the given values to the hash.
+func (h *hasher) write(vs ...uint32) {
+	var buf [4]byte
+	for _, v := range vs {
+		binary.LittleEndian.PutUint32(buf[:], v)
+		h.Write(buf[:])
+	}
+}
+
+// str writes the given string to the hash.
+func (h *hasher) str(s string) {
+	h.write(uint32(len(s)))
+	h.Write([]byte(s))
+}
@@ -0,0 +1,63 @@
+package c_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym/csym/c"
+)
+
+func TestEqual(t *testing.T) {
+	// list returns a self-referential struct with a nested anonymous union.
+	list := func(tag, unionTag string) *c.StructType {
+		u := &c.UnionType{
+			Size: 4,
+			Tag:  unionTag,
+			Fields: []c.Field{
+				{Var: c.Var{Type: c.Int, Name: "i"}},
+				{Var: c.Var{Type: &c.PointerType{Elem: c.Char}, Name: "s"}},
+			},
+		}
+		t := &c.StructType{
+			Size: 8,
+			Tag:  tag,
+		}
+		t.Fields = []c.Field{
+			{Offset: 0, Size: 4, Var: c.Var{Type: &c.PointerType{Elem: t}, Name: "next"}},
+			{Offset: 4, Size: 4, Var: c.Var{Type: u, Name: "val"}},
+		}
+		return t
+	}
+	golden := []struct {
+		a, b c.Type
+		cmp  c.Comparer
+		want bool
+	}{
+		{a: c.Int, b: c.Int, want: true},
+		{a: c.Int, b: c.UInt, want: false},
+		{a: list("node", "_1fake"), b: list("node", "_1fake"), want: true},
+		{a: list("node", "_1fake"), b: list("node", "_7fake"), want: false},
+		{a: list("node", "_1fake"), b: list("node", "_7fake"), cmp: c.Comparer{IgnoreFakeTags: true}, want: true},
+		{a: list("node", "_1fake"), b: list("elem", "_7fake"), cmp: c.Comparer{IgnoreFakeTags: true}, want: false},
+		{a: list("node", "value"), b: list("elem", "union"), cmp: c.Comparer{IgnoreTags: true}, want: true},
+		{a: list("node", "value"), b: list("node", "_1fake"), cmp: c.Comparer{IgnoreFakeTags: true}, want: false},
+		{
+			a:    &c.ArrayType{Elem: &c.PointerType{Elem: c.Short}, Len: 3},
+			b:    &c.ArrayType{Elem: &c.PointerType{Elem: c.Short}, Len: 3},
+			want: true,
+		},
+		{
+			a:    &c.ArrayType{Elem: c.Short, Len: 3},
+			b:    &c.ArrayType{Elem: c.Short, Len: 4},
+			want: false,
+		},
+	}
+	for i, g := range golden {
+		got := g.cmp.Equal(g.a, g.b)
+		if g.want != got {
+			t.Errorf("%d: equality mismatch between %v and %v; expected %v, got %v", i, g.a, g.b, g.want, got)
+		}
+		if got && g.cmp.Hash(g.a) != g.cmp.Hash(g.b) {
+			t.Errorf("%d: hash mismatch between identical types %v and %v", i, g.a, g.b)
+		}
+	}
+}
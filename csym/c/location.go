@@ -0,0 +1,75 @@
+package c
+
+import "fmt"
+
+// A Location is the storage location of a variable.
+//
+// Location may have one of the following underlying types.
+//
+//	*StaticLoc
+//	*StackLoc
+//	*RegLoc
+type Location interface {
+	fmt.Stringer
+	// isLocation ensures that only storage locations can be assigned to the
+	// c.Location interface.
+	isLocation()
+}
+
+// A StaticLoc is a static storage location.
+type StaticLoc struct {
+	// Absolute address.
+	Addr uint32
+}
+
+// String returns the string representation of the static storage location.
+func (loc *StaticLoc) String() string {
+	return fmt.Sprintf("0x%08X", loc.Addr)
+}
+
+// A StackLoc is a stack-resident storage location.
+type StackLoc struct {
+	// Offset relative to the frame pointer.
+	Offset int32
+}
+
+// String returns the string representation of the stack storage location.
+func (loc *StackLoc) String() string {
+	return frameOffset(loc.Offset)
+}
+
+// A RegLoc is a register-resident storage location.
+type RegLoc struct {
+	// Register.
+	Reg Reg
+}
+
+// String returns the string representation of the register storage location.
+func (loc *RegLoc) String() string {
+	return loc.Reg.String()
+}
+
+// isLocation ensures that only storage locations can be assigned to the
+// c.Location interface.
+func (*StaticLoc) isLocation() {}
+func (*StackLoc) isLocation()  {}
+func (*RegLoc) isLocation()    {}
+
+// A Reg is a MIPS general purpose register.
+type Reg uint32
+
+// regNames maps from register number to MIPS register name.
+var regNames = [...]string{
+	"$zero", "$at", "$v0", "$v1", "$a0", "$a1", "$a2", "$a3",
+	"$t0", "$t1", "$t2", "$t3", "$t4", "$t5", "$t6", "$t7",
+	"$s0", "$s1", "$s2", "$s3", "$s4", "$s5", "$s6", "$s7",
+	"$t8", "$t9", "$k0", "$k1", "$gp", "$sp", "$fp", "$ra",
+}
+
+// String returns the MIPS name of the register (e.g. "$s1").
+func (reg Reg) String() string {
+	if int(reg) < len(regNames) {
+		return regNames[reg]
+	}
+	return fmt.Sprintf("$%d", uint32(reg))
+}
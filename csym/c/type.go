@@ -50,8 +50,8 @@ type StructType struct {
 	Tag string
 	// Structure fields.
 	Fields []Field
-	// Struct methods.
-	Methods []Field
+	// C++ class information (optional).
+	Class *Class
 }
 
 // String returns the string representation of the structure type.
@@ -78,15 +78,14 @@ func (t *StructType) Def() string {
 		}
 		fmt.Fprintf(buf, "\t%s;\n", field)
 	}
-	// TODO: Figure out how to print methods in a good way; for now, commented
-	// out.
-	for _, method := range t.Methods {
-		if method.Size > 0 {
-			fmt.Fprintf(buf, "\t// offset: %04X (%d bytes)\n", method.Offset, method.Size)
-		} else if len(t.Fields) > 1 && t.Fields[1].Offset > 0 {
-			fmt.Fprintf(buf, "\t// offset: %04X\n", method.Offset)
+	// C lacks member functions and static data members; output as comments.
+	if t.Class != nil {
+		for _, m := range t.Class.Statics {
+			fmt.Fprintf(buf, "\t// %s;\n", m)
+		}
+		for _, m := range t.Class.Methods {
+			fmt.Fprintf(buf, "\t// %s;\n", m.decl(t.Tag))
 		}
-		fmt.Fprintf(buf, "\t// %s;\n", method)
 	}
 	buf.WriteString("}")
 	return buf.String()
@@ -136,9 +135,15 @@ func (t *UnionType) Def() string {
 
 // EnumType is a enum type.
 type EnumType struct {
+	// Size in bytes of enum storage (optional); inferred from uses of the enum.
+	Size uint32
+	// Distinct sizes in bytes of the uses of the enum, in order of occurrence;
+	// only recorded if the uses disagree on the size, in which case Size holds
+	// the largest.
+	UseSizes []uint32
 	// Enum tag.
 	Tag string
-	// Enum members.
+	// Enum members in order of declaration.
 	Members []*EnumMember
 }
 
@@ -150,20 +155,23 @@ func (t *EnumType) String() string {
 // Def returns the C syntax representation of the definition of the type.
 func (t *EnumType) Def() string {
 	buf := &strings.Builder{}
+	if t.Size > 0 {
+		fmt.Fprintf(buf, "// size: 0x%X\n", t.Size)
+	}
+	if len(t.UseSizes) > 0 {
+		sizes := make([]string, len(t.UseSizes))
+		for i, size := range t.UseSizes {
+			sizes[i] = fmt.Sprintf("0x%X", size)
+		}
+		fmt.Fprintf(buf, "// conflicting use sizes: %s\n", strings.Join(sizes, ", "))
+	}
 	if len(t.Tag) > 0 {
 		fmt.Fprintf(buf, "enum %s {\n", t.Tag)
 	} else {
 		buf.WriteString("enum {\n")
 	}
-	less := func(i, j int) bool {
-		if t.Members[i].Value == t.Members[j].Value {
-			return t.Members[i].Name < t.Members[j].Name
-		}
-		return t.Members[i].Value < t.Members[j].Value
-	}
-	sort.Slice(t.Members, less)
 	w := tabwriter.NewWriter(buf, 1, 3, 1, ' ', tabwriter.TabIndent)
-	for _, member := range t.Members {
+	for _, member := range t.SortedMembers() {
 		fmt.Fprintf(w, "\t%s\t= %d,\n", member.Name, member.Value)
 	}
 	if err := w.Flush(); err != nil {
@@ -173,12 +181,25 @@ func (t *EnumType) Def() string {
 	return buf.String()
 }
 
+// SortedMembers returns the members of the enum sorted by value.
+func (t *EnumType) SortedMembers() []*EnumMember {
+	ms := append([]*EnumMember(nil), t.Members...)
+	less := func(i, j int) bool {
+		if ms[i].Value == ms[j].Value {
+			return ms[i].Name < ms[j].Name
+		}
+		return ms[i].Value < ms[j].Value
+	}
+	sort.Slice(ms, less)
+	return ms
+}
+
 // ~~~ [ Enum member ] ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 
 // EnumMember is an enum member.
 type EnumMember struct {
 	// Enum value.
-	Value uint32
+	Value int32
 	// Enum name.
 	Name string
 }
@@ -234,6 +255,9 @@ type FuncType struct {
 	Params []*VarDecl
 	// Variadic function.
 	Variadic bool
+	// Parameter list is known; an empty parameter list is represented as
+	// (void).
+	Prototyped bool
 }
 
 // String returns the string representation of the function type.
@@ -248,6 +272,27 @@ func (t *FuncType) Def() string {
 	return t.String()
 }
 
+// --- [ Typedef type ] -------------------------------------------------------
+
+// TypedefType is a reference to a type definition.
+type TypedefType struct {
+	// Type definition name.
+	Name string
+	// Underlying type.
+	Type Type
+}
+
+// String returns the string representation of the typedef type.
+func (t *TypedefType) String() string {
+	return t.Name
+}
+
+// Def returns the C syntax representation of the definition of the type.
+func (t *TypedefType) Def() string {
+	v := Var{Type: t.Type, Name: t.Name}
+	return fmt.Sprintf("typedef %s", v)
+}
+
 // ### [ Helper types ] ########################################################
 
 // A Field represents a field in a structure type or union type.
@@ -307,12 +352,15 @@ func (v Var) String() string {
 			}
 			buf.WriteString("...")
 		}
+		if t.Prototyped && len(t.Params) == 0 && !t.Variadic {
+			buf.WriteString("void")
+		}
 		buf.WriteString(")")
 		v.Name = buf.String()
 		v.Type = t.RetType
 		return v.String()
 	case *UnionType:
-		if isFakeTag(t.Tag) {
+		if IsFakeTag(t.Tag) {
 			return fmt.Sprintf("%s %s", fakeUnionString(t), v.Name)
 		}
 		return fmt.Sprintf("%s %s", t, v.Name)
@@ -341,9 +389,9 @@ func fakeUnionString(t *UnionType) string {
 	return buf.String()
 }
 
-// isFakeTag reports whether the tag name is fake (generated by the compiler for
+// IsFakeTag reports whether the tag name is fake (generated by the compiler for
 // symbols lacking a tag name).
-func isFakeTag(tag string) bool {
+func IsFakeTag(tag string) bool {
 	if strings.HasPrefix(tag, "_") && strings.HasSuffix(tag, "fake") {
 		s := tag[len("_") : len(tag)-len("fake")]
 		_, err := strconv.Atoi(s)
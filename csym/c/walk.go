@@ -0,0 +1,249 @@
+package c
+
+// A Node is a node of the C AST.
+//
+// Node may have one of the following underlying types.
+//
+//	Type (BaseType, *StructType, *UnionType, *EnumType, *PointerType,
+//	      *ArrayType, *FuncType, *TypedefType, *VarDecl, *TagDecl)
+//	*FuncDecl
+//	*Block
+//	*Field
+type Node interface{}
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the AST in depth-first order, starting at the given node.
+// Structure, union and typedef types, and variable declarations are visited at
+// most once, to protect against cycles of self-referential types.
+func Walk(v Visitor, node Node) {
+	w := &walker{visited: make(map[Node]bool)}
+	w.walk(v, node)
+}
+
+// walker tracks visited nodes during traversal.
+type walker struct {
+	// visited tracks visited named types and declarations.
+	visited map[Node]bool
+}
+
+// walk traverses the AST in depth-first order, starting at the given node.
+func (w *walker) walk(v Visitor, node Node) {
+	switch node.(type) {
+	case *StructType, *UnionType, *TypedefType, *VarDecl, *FuncDecl:
+		if w.visited[node] {
+			return
+		}
+		w.visited[node] = true
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case *StructType:
+		for i := range n.Fields {
+			w.walk(v, &n.Fields[i])
+		}
+		if n.Class != nil {
+			for _, base := range n.Class.Bases {
+				w.walk(v, base.Type)
+			}
+			for _, m := range n.Class.Methods {
+				if m.Type != nil {
+					w.walk(v, m.Type)
+				}
+			}
+			for _, m := range n.Class.Statics {
+				w.walk(v, m.Type)
+			}
+		}
+	case *UnionType:
+		for i := range n.Fields {
+			w.walk(v, &n.Fields[i])
+		}
+	case *PointerType:
+		w.walk(v, n.Elem)
+	case *ArrayType:
+		w.walk(v, n.Elem)
+	case *FuncType:
+		w.walk(v, n.RetType)
+		for _, param := range n.Params {
+			w.walk(v, param)
+		}
+	case *TypedefType:
+		w.walk(v, n.Type)
+	case *VarDecl:
+		w.walk(v, n.Type)
+	case *TagDecl:
+		w.walk(v, n.Type)
+	case *Field:
+		w.walk(v, n.Type)
+	case *FuncDecl:
+		w.walk(v, n.Type)
+		for _, block := range n.Blocks {
+			w.walk(v, block)
+		}
+	case *Block:
+		for _, local := range n.Locals {
+			w.walk(v, local)
+		}
+		for _, block := range n.Blocks {
+			w.walk(v, block)
+		}
+	}
+	v.Visit(nil)
+}
+
+// inspector is a visitor which invokes a function for each node.
+type inspector func(Node) bool
+
+// Visit invokes the inspector function for the given node.
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the AST in depth-first order, starting at the given node.
+// It starts by calling f(node); if f returns true, Inspect invokes f
+// recursively for each of the children of node, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// --- [ Rewriting ] -----------------------------------------------------------
+
+// A Rewriter replaces types in place. The replacement function is invoked for
+// each type reference; e.g. the type of fields and declarations, and the
+// element type of pointers and arrays. The children of replacement types are
+// rewritten in turn.
+//
+// Structure, union and typedef types, and variable declarations are rewritten
+// at most once per Rewriter, to protect against cycles of self-referential
+// types.
+type Rewriter struct {
+	// Replacement function; returns the replacement of the given type, or the
+	// type itself.
+	replace func(Type) Type
+	// done tracks rewritten named types and declarations.
+	done map[Node]bool
+}
+
+// NewRewriter returns a new rewriter based on the given replacement function.
+func NewRewriter(replace func(Type) Type) *Rewriter {
+	return &Rewriter{
+		replace: replace,
+		done:    make(map[Node]bool),
+	}
+}
+
+// Rewrite rewrites the types referred to by the given node in place. If the
+// node is a type (other than a variable declaration) it may itself be
+// replaced; the resulting node is returned.
+func Rewrite(node Node, replace func(Type) Type) Node {
+	return NewRewriter(replace).Rewrite(node)
+}
+
+// Rewrite rewrites the types referred to by the given node in place. If the
+// node is a type (other than a variable declaration) it may itself be
+// replaced; the resulting node is returned.
+func (r *Rewriter) Rewrite(node Node) Node {
+	switch n := node.(type) {
+	case *VarDecl:
+		r.rewriteDecl(n)
+	case *FuncDecl:
+		if r.done[n] {
+			return n
+		}
+		r.done[n] = true
+		n.Type = r.rewriteType(n.Type)
+		for _, block := range n.Blocks {
+			r.Rewrite(block)
+		}
+	case *Block:
+		for _, local := range n.Locals {
+			r.rewriteDecl(local)
+		}
+		for _, block := range n.Blocks {
+			r.Rewrite(block)
+		}
+	case *Field:
+		n.Type = r.rewriteType(n.Type)
+	case Type:
+		return r.rewriteType(n)
+	}
+	return node
+}
+
+// rewriteDecl rewrites the type of the given variable declaration in place.
+func (r *Rewriter) rewriteDecl(v *VarDecl) {
+	if r.done[v] {
+		return
+	}
+	r.done[v] = true
+	v.Type = r.rewriteType(v.Type)
+}
+
+// rewriteType returns the replacement of the given type, after rewriting its
+// children in place.
+func (r *Rewriter) rewriteType(t Type) Type {
+	if t == nil {
+		return nil
+	}
+	t = r.replace(t)
+	if r.done[t] {
+		return t
+	}
+	switch t := t.(type) {
+	case *StructType:
+		r.done[t] = true
+		for i := range t.Fields {
+			t.Fields[i].Type = r.rewriteType(t.Fields[i].Type)
+		}
+		if t.Class != nil {
+			for _, base := range t.Class.Bases {
+				// Base classes are only replaced by structure types.
+				if u, ok := r.rewriteType(base.Type).(*StructType); ok {
+					base.Type = u
+				}
+			}
+			for _, m := range t.Class.Methods {
+				// Methods are only rewritten in place.
+				if m.Type != nil {
+					r.rewriteType(m.Type)
+				}
+			}
+			for _, m := range t.Class.Statics {
+				m.Type = r.rewriteType(m.Type)
+			}
+		}
+	case *UnionType:
+		r.done[t] = true
+		for i := range t.Fields {
+			t.Fields[i].Type = r.rewriteType(t.Fields[i].Type)
+		}
+	case *TypedefType:
+		r.done[t] = true
+		t.Type = r.rewriteType(t.Type)
+	case *VarDecl:
+		r.rewriteDecl(t)
+	case *TagDecl:
+		t.Type = r.rewriteType(t.Type)
+	case *PointerType:
+		t.Elem = r.rewriteType(t.Elem)
+	case *ArrayType:
+		t.Elem = r.rewriteType(t.Elem)
+	case *FuncType:
+		t.RetType = r.rewriteType(t.RetType)
+		for _, param := range t.Params {
+			r.rewriteDecl(param)
+		}
+	}
+	return t
+}
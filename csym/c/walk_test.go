@@ -0,0 +1,53 @@
+package c_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym/csym/c"
+)
+
+func TestRewrite(t *testing.T) {
+	// struct node { struct node *next; u_long val; };
+	ulong := &c.TypedefType{Name: "u_long", Type: c.ULong}
+	node := &c.StructType{Size: 8, Tag: "node"}
+	node.Fields = []c.Field{
+		{Offset: 0, Size: 4, Var: c.Var{Type: &c.PointerType{Elem: node}, Name: "next"}},
+		{Offset: 4, Size: 4, Var: c.Var{Type: ulong, Name: "val"}},
+	}
+	f := &c.FuncDecl{
+		Var: c.Var{
+			Type: &c.FuncType{
+				RetType: ulong,
+				Params: []*c.VarDecl{
+					{Var: c.Var{Type: &c.PointerType{Elem: node}, Name: "n"}},
+				},
+			},
+			Name: "sum",
+		},
+	}
+	// Substitute the type definition by its underlying type.
+	c.Rewrite(f, func(t c.Type) c.Type {
+		if def, ok := t.(*c.TypedefType); ok && def.Name == "u_long" {
+			return def.Type
+		}
+		return t
+	})
+	if got, want := f.Var.String(), "unsigned long sum(struct node *n)"; got != want {
+		t.Errorf("function mismatch; expected %q, got %q", want, got)
+	}
+	if got := node.Fields[1].Type; got != c.ULong {
+		t.Errorf("field type mismatch; expected %v, got %v", c.ULong, got)
+	}
+	// Count the structure types reachable from the function; the
+	// self-referential struct is visited once.
+	n := 0
+	c.Inspect(f, func(node c.Node) bool {
+		if _, ok := node.(*c.StructType); ok {
+			n++
+		}
+		return true
+	})
+	if n != 1 {
+		t.Errorf("struct count mismatch; expected 1, got %d", n)
+	}
+}
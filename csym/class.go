@@ -0,0 +1,209 @@
+package csym
+
+import (
+	"strings"
+
+	"github.com/sanctuary/sym"
+	"github.com/sanctuary/sym/csym/c"
+	"github.com/sanctuary/sym/demangle"
+)
+
+// Struct tag of C++ virtual tables.
+const vtblPtrTag = "__vtbl_ptr_type"
+
+// parseClassMember parses a C++ class member (FIELD) symbol; i.e. a member
+// function or static data member.
+func (p *Parser) parseClassMember(t *c.StructType, body *sym.Def) {
+	name := validName(body.Name)
+	typ := p.parseType(body.Type, nil, "")
+	class := classOf(t)
+	if funcType, ok := typ.(*c.FuncType); ok {
+		method := &c.Method{
+			Name: name,
+			Type: funcType,
+		}
+		class.Methods = append(class.Methods, method)
+		return
+	}
+	static := &c.StaticMember{
+		Name: name,
+		Type: typ,
+	}
+	class.Statics = append(class.Statics, static)
+}
+
+// recoverClassTypes recovers the C++ class information of structure types
+// which is derived from types alone; i.e. virtual table pointers and base class
+// subobjects.
+func (p *Parser) recoverClassTypes() {
+	// Virtual table pointers.
+	for _, tag := range p.StructTags {
+		t := p.Structs[tag]
+		for i := range t.Fields {
+			if isVtblPtr(t.Fields[i]) {
+				classOf(t).VtblPtr = &t.Fields[i]
+				break
+			}
+		}
+	}
+	p.recoverBases()
+}
+
+// recoverClassMembers recovers the C++ class information of structure types
+// which is derived from declarations; i.e. member functions and static data
+// members (based on mangled names of function and variable declarations), and
+// base class subobjects of classes thus identified.
+func (p *Parser) recoverClassMembers() {
+	for _, overlay := range append([]*Overlay{p.Overlay}, p.Overlays...) {
+		for _, f := range overlay.Funcs {
+			if t, s, ok := p.memberOf(f.LinkageName); ok {
+				switch s.Kind {
+				case demangle.KindMethod, demangle.KindCtor, demangle.KindDtor:
+					linkMethod(classOf(t), s.Name, f)
+				}
+			}
+		}
+		for _, v := range overlay.Vars {
+			if t, s, ok := p.memberOf(v.LinkageName); ok && s.Kind == demangle.KindStatic {
+				linkStatic(classOf(t), s.Name, v)
+			}
+		}
+	}
+	p.recoverBases()
+}
+
+// recoverBases recovers the base class subobjects of C++ classes, for classes
+// without recovered base classes.
+func (p *Parser) recoverBases() {
+	for _, tag := range p.StructTags {
+		t := p.Structs[tag]
+		if t.Class != nil && len(t.Class.Bases) > 0 {
+			continue
+		}
+		var bases []*c.BaseClass
+		for i, field := range t.Fields {
+			base, ok := underlying(field.Type).(*c.StructType)
+			if !ok || field.Name != base.Tag || (t.Class == nil && base.Class == nil) {
+				break
+			}
+			b := &c.BaseClass{
+				Offset: field.Offset,
+				Type:   base,
+				Index:  i,
+			}
+			bases = append(bases, b)
+		}
+		if len(bases) > 0 {
+			classOf(t).Bases = bases
+		}
+	}
+}
+
+// linkMethod links the member function of the given class to its function
+// declaration.
+func linkMethod(class *c.Class, name string, f *c.FuncDecl) {
+	funcType, ok := f.Type.(*c.FuncType)
+	if !ok {
+		return
+	}
+	for _, m := range class.Methods {
+		if m.Func == f {
+			// Already linked.
+			return
+		}
+	}
+	for _, m := range class.Methods {
+		// Names of member functions in FIELD symbols are made valid by validName.
+		if (m.Name == name || m.Name == validName(name)) && m.Func == nil {
+			m.Name = name
+			m.Func = f
+			m.Type = funcType
+			return
+		}
+	}
+	m := &c.Method{
+		Name: name,
+		Type: funcType,
+		Func: f,
+	}
+	class.Methods = append(class.Methods, m)
+}
+
+// linkStatic links the static data member of the given class to its variable
+// declaration.
+func linkStatic(class *c.Class, name string, v *c.VarDecl) {
+	for _, m := range class.Statics {
+		if m.Var == v {
+			// Already linked.
+			return
+		}
+	}
+	for _, m := range class.Statics {
+		if m.Name == name && m.Var == nil {
+			m.Var = v
+			return
+		}
+	}
+	m := &c.StaticMember{
+		Name: name,
+		Type: v.Type,
+		Var:  v,
+	}
+	class.Statics = append(class.Statics, m)
+}
+
+// classOf returns the C++ class information of the given structure type,
+// adding it if not present.
+func classOf(t *c.StructType) *c.Class {
+	if t.Class == nil {
+		t.Class = &c.Class{}
+	}
+	return t.Class
+}
+
+// isVtblPtr reports whether the given field is a virtual table pointer.
+func isVtblPtr(field c.Field) bool {
+	if strings.HasPrefix(field.Name, "_vptr") {
+		return true
+	}
+	if t, ok := field.Type.(*c.PointerType); ok {
+		if elem, ok := t.Elem.(*c.StructType); ok {
+			return elem.Tag == vtblPtrTag
+		}
+	}
+	return false
+}
+
+// underlying returns the underlying type of the given type, after resolving
+// type definitions.
+func underlying(t c.Type) c.Type {
+	for {
+		def, ok := t.(*c.TypedefType)
+		if !ok {
+			return t
+		}
+		t = def.Type
+	}
+}
+
+// memberOf returns the structure type of the class and the demangled symbol of
+// the given C++ class member linkage name.
+func (p *Parser) memberOf(linkageName string) (*c.StructType, *demangle.Symbol, bool) {
+	if len(linkageName) == 0 {
+		return nil, nil, false
+	}
+	s, err := demangle.Parse(linkageName)
+	if err != nil || len(s.Class) == 0 {
+		return nil, nil, false
+	}
+	// Structure tags of nested classes are unqualified.
+	tag := s.Class
+	if pos := strings.LastIndex(tag, "::"); pos != -1 {
+		tag = tag[pos+len("::"):]
+	}
+	t, ok := p.Structs[validName(tag)]
+	if !ok {
+		return nil, nil, false
+	}
+	return t, s, true
+}
@@ -0,0 +1,35 @@
+package csym_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym"
+)
+
+func TestRecoverClasses(t *testing.T) {
+	syms := []*sym.Symbol{
+		// struct Base { int id; };
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 4, "Base"),
+		def(0, sym.ClassMOS, sym.Type(sym.BaseInt), 4, "id"),
+		eos(),
+		// typedef struct Base BaseT;
+		def2(0, sym.ClassTPDEF, sym.Type(sym.BaseStruct), 4, nil, "Base", "BaseT"),
+		// class Derived : public Base { int x; int Get(); };
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 8, "Derived"),
+		def2(0, sym.ClassMOS, sym.Type(sym.BaseStruct), 4, nil, "BaseT", "Base"),
+		def(4, sym.ClassMOS, sym.Type(sym.BaseInt), 4, "x"),
+		def(0, sym.ClassFIELD, tFcn|sym.Type(sym.BaseInt), 0, "Get"),
+		eos(),
+	}
+	p := parse(t, syms)
+	want := `// size: 0x8
+class Derived : public Base {
+public:
+	// offset: 0004 (4 bytes)
+	int x;
+	int Get();
+}`
+	if got := p.Structs["Derived"].ClassDef(); got != want {
+		t.Errorf("class definition mismatch; expected:\n%s\ngot:\n%s", want, got)
+	}
+}
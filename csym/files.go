@@ -0,0 +1,191 @@
+package csym
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// Confidence scores of inferred source files of global variables.
+const (
+	// Address of a static variable nested in a function of the source file.
+	confFuncStatic = 1.0
+	// Address within the object module of the source file.
+	confObjModule = 0.9
+	// Between functions of the same source file in SYM order.
+	confSymOrder = 0.8
+	// Between global variables of the same source file in address order.
+	confAddrBetween = 0.6
+	// Next to a function of the source file in SYM order.
+	confSymNeighbor = 0.5
+	// Next to a global variable of the source file in address order.
+	confAddrNeighbor = 0.3
+)
+
+// symPaths records the source files of the functions preceding and succeeding
+// a global variable declaration in SYM order.
+type symPaths struct {
+	// Source file of preceding function.
+	prev string
+	// Source file of succeeding function.
+	next string
+}
+
+// trackFuncPath records the source file of the function currently being
+// parsed, as used to infer source files of global variables.
+func (p *Parser) trackFuncPath(path string) {
+	for _, v := range p.pendingVars {
+		p.varSymPaths[v].next = path
+	}
+	p.pendingVars = p.pendingVars[:0]
+	p.lastPath = path
+}
+
+// resetFuncPath resets the source file tracking of global variables in SYM
+// order, as the functions and global variables of different overlays are not
+// interleaved.
+func (p *Parser) resetFuncPath() {
+	p.pendingVars = p.pendingVars[:0]
+	p.lastPath = ""
+}
+
+// trackVar records the source file of the function preceding the given global
+// variable declaration in SYM order.
+func (p *Parser) trackVar(v *c.VarDecl) {
+	p.varSymPaths[v] = &symPaths{prev: p.lastPath}
+	p.pendingVars = append(p.pendingVars, v)
+}
+
+// inferVarPaths infers the defining source file of global variables, based on
+// static variables nested in the line ranges of functions, object module linker
+// symbols, the position of global variables in SYM order
+// relative to functions, and address adjacency to global variables of known
+// source file.
+func (p *Parser) inferVarPaths() {
+	for _, overlay := range append([]*Overlay{p.Overlay}, p.Overlays...) {
+		p.inferOverlayVarPaths(overlay)
+	}
+}
+
+// inferOverlayVarPaths infers the defining source file of the global variables
+// of the given overlay.
+func (p *Parser) inferOverlayVarPaths(overlay *Overlay) {
+	// Source files by object module name.
+	paths := make(map[string]string)
+	for _, f := range overlay.Funcs {
+		if len(f.Path) > 0 {
+			paths[strings.ToLower(pathStem(f.Path))] = f.Path
+		}
+	}
+	// Sections of object modules by source file.
+	var sects []*Section
+	var sectPaths []string
+	for _, module := range overlay.Modules() {
+		for _, sect := range module.Sections {
+			name := module.Name
+			if len(name) == 0 {
+				name = sect.Name
+			}
+			if path, ok := paths[strings.ToLower(name)]; ok {
+				sects = append(sects, sect)
+				sectPaths = append(sectPaths, path)
+			}
+		}
+	}
+	// Source files by address of static variables nested in functions.
+	staticPaths := make(map[uint32]string)
+	for _, f := range overlay.Funcs {
+		if len(f.Path) == 0 {
+			continue
+		}
+		for _, block := range f.AllBlocks() {
+			for _, local := range block.Locals {
+				if loc, ok := local.Loc.(*c.StaticLoc); ok {
+					staticPaths[loc.Addr] = f.Path
+				}
+			}
+		}
+	}
+	for _, v := range overlay.Vars {
+		// Static variable nested in function.
+		if path, ok := staticPaths[v.Addr()]; ok {
+			setVarPath(v, path, confFuncStatic)
+		}
+		// Object module.
+		for i, sect := range sects {
+			if sect.Contains(v.Addr()) {
+				setVarPath(v, sectPaths[i], confObjModule)
+				break
+			}
+		}
+		// SYM order.
+		if sp, ok := p.varSymPaths[v]; ok {
+			switch {
+			case len(sp.prev) > 0 && sp.prev == sp.next:
+				setVarPath(v, sp.prev, confSymOrder)
+			case len(sp.prev) > 0:
+				setVarPath(v, sp.prev, confSymNeighbor)
+			case len(sp.next) > 0:
+				setVarPath(v, sp.next, confSymNeighbor)
+			}
+		}
+	}
+	// Address adjacency to global variables of source files inferred with high
+	// confidence.
+	vars := make([]*c.VarDecl, len(overlay.Vars))
+	copy(vars, overlay.Vars)
+	sort.SliceStable(vars, func(i, j int) bool {
+		return vars[i].Addr() < vars[j].Addr()
+	})
+	isAnchor := func(v *c.VarDecl) bool {
+		return v.PathConfidence >= confSymOrder
+	}
+	for i, v := range vars {
+		if isAnchor(v) {
+			continue
+		}
+		var prev, next string
+		for j := i - 1; j >= 0; j-- {
+			if isAnchor(vars[j]) {
+				prev = vars[j].Path
+				break
+			}
+		}
+		for j := i + 1; j < len(vars); j++ {
+			if isAnchor(vars[j]) {
+				next = vars[j].Path
+				break
+			}
+		}
+		switch {
+		case len(prev) > 0 && prev == next:
+			setVarPath(v, prev, confAddrBetween)
+		case len(prev) > 0:
+			setVarPath(v, prev, confAddrNeighbor)
+		case len(next) > 0:
+			setVarPath(v, next, confAddrNeighbor)
+		}
+	}
+}
+
+// setVarPath sets the source file of the given global variable, unless already
+// inferred with higher confidence.
+func setVarPath(v *c.VarDecl, path string, conf float64) {
+	if conf > v.PathConfidence {
+		v.Path = path
+		v.PathConfidence = conf
+	}
+}
+
+// pathStem returns the file name of the given source path (DOS or UNIX),
+// without extension.
+func pathStem(path string) string {
+	if pos := strings.LastIndexAny(path, `\/:`); pos != -1 {
+		path = path[pos+1:]
+	}
+	if pos := strings.LastIndex(path, "."); pos != -1 {
+		path = path[:pos]
+	}
+	return path
+}
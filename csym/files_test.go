@@ -0,0 +1,68 @@
+package csym_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym"
+)
+
+func TestInferVarPaths(t *testing.T) {
+	const (
+		pathA = `C:\GAME\A.C`
+		pathB = `C:\GAME\B.C`
+		pathC = `C:\GAME\C.C`
+	)
+	syms := []*sym.Symbol{
+		def(0x80010000, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "a"),
+		def(0x80010100, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "b"),
+		// void a(void) { static int count; }
+		newSym(0x80010000, &sym.FuncStart{FP: 29, FSize: 8, RetReg: 31, Line: 10, Path: pathA, Name: "a"}),
+		newSym(0x80010008, &sym.BlockStart{Line: 1}),
+		def(0x80090010, sym.ClassSTAT, sym.Type(sym.BaseInt), 4, "count"),
+		newSym(0x80010010, &sym.BlockEnd{Line: 3}),
+		newSym(0x80010018, &sym.FuncEnd{Line: 3}),
+		// static int between; between functions of A.C and B.C.
+		def(0x80090000, sym.ClassSTAT, sym.Type(sym.BaseInt), 4, "between"),
+		// void b(void) {}
+		newSym(0x80010100, &sym.FuncStart{FP: 29, FSize: 8, RetReg: 31, Line: 20, Path: pathB, Name: "b"}),
+		newSym(0x80010108, &sym.BlockStart{Line: 1}),
+		newSym(0x80010110, &sym.BlockEnd{Line: 2}),
+		newSym(0x80010118, &sym.FuncEnd{Line: 2}),
+		// static int count; after function of B.C, nested in function of A.C.
+		def(0x80090010, sym.ClassSTAT, sym.Type(sym.BaseInt), 4, "count"),
+		// Overlay 1.
+		newSym(0x80100000, &sym.Overlay{Length: 0x1000, ID: 1}),
+		newSym(1, &sym.SetOverlay{}),
+		// static int first; before functions of overlay.
+		def(0x80100800, sym.ClassSTAT, sym.Type(sym.BaseInt), 4, "first"),
+		def(0x80100000, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "c"),
+		// void c(void) {}
+		newSym(0x80100000, &sym.FuncStart{FP: 29, FSize: 8, RetReg: 31, Line: 30, Path: pathC, Name: "c"}),
+		newSym(0x80100008, &sym.BlockStart{Line: 1}),
+		newSym(0x80100010, &sym.BlockEnd{Line: 2}),
+		newSym(0x80100018, &sym.FuncEnd{Line: 2}),
+	}
+	p := parse(t, syms)
+	golden := map[string]struct {
+		path string
+		conf float64
+	}{
+		"between": {path: pathA, conf: 0.5},
+		"count":   {path: pathA, conf: 1.0},
+		"first":   {path: pathC, conf: 0.5},
+	}
+	for _, v := range append(p.Vars, p.Overlays[0].Vars...) {
+		want, ok := golden[v.Name]
+		if !ok {
+			t.Errorf("unexpected global variable %q", v.Name)
+			continue
+		}
+		if v.Path != want.path || v.PathConfidence != want.conf {
+			t.Errorf("source file of %q mismatch; expected %q (confidence: %.2f), got %q (confidence: %.2f)", v.Name, want.path, want.conf, v.Path, v.PathConfidence)
+		}
+		delete(golden, v.Name)
+	}
+	for name := range golden {
+		t.Errorf("global variable %q not found", name)
+	}
+}
@@ -0,0 +1,239 @@
+package csym
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// A Module is an object module linked into the executable, as described by
+// linker symbols.
+type Module struct {
+	// Module name; empty for sections of the executable not associated with a
+	// specific object module.
+	Name string
+	// Sections of the object module.
+	Sections []*Section
+}
+
+// A Section is a linker section (or group), as described by linker symbols.
+//
+//	<name>_org       Org
+//	<name>_orgend    OrgEnd
+//	<name>_obj       Obj
+//	<name>_objend    ObjEnd
+//	<name>_size      Size
+type Section struct {
+	// Section name.
+	Name string
+	// Origin address.
+	Org uint32
+	// End of origin address.
+	OrgEnd uint32
+	// Object address.
+	Obj uint32
+	// End of object address.
+	ObjEnd uint32
+	// Size in bytes.
+	Size uint32
+}
+
+// Start returns the start address of the section.
+func (sect *Section) Start() uint32 {
+	if sect.Org != 0 {
+		return sect.Org
+	}
+	return sect.Obj
+}
+
+// End returns the end address of the section.
+func (sect *Section) End() uint32 {
+	switch {
+	case sect.OrgEnd != 0:
+		return sect.OrgEnd
+	case sect.ObjEnd != 0:
+		return sect.ObjEnd
+	}
+	if start := sect.Start(); start != 0 {
+		return start + sect.Size
+	}
+	return 0
+}
+
+// Contains reports whether the given address is within the section.
+func (sect *Section) Contains(addr uint32) bool {
+	return sect.Start() <= addr && addr < sect.End()
+}
+
+// Linker symbol suffixes of section properties.
+const (
+	suffixOrgEnd = "_orgend"
+	suffixOrg    = "_org"
+	suffixObjEnd = "_objend"
+	suffixObj    = "_obj"
+	suffixSize   = "_size"
+)
+
+// Modules returns the object modules and sections of the overlay, as
+// reconstructed from linker symbols, sorted by module name. Sections of the
+// executable not associated with a specific object module are contained in a
+// module with empty name.
+//
+// Linker symbols of sections of object modules have the form
+// "__<module>_<section>_<suffix>" (e.g. "__RHS2_data_size").
+func (overlay *Overlay) Modules() []*Module {
+	modules := make(map[string]*Module)
+	sects := overlay.sections()
+	for _, prefix := range sortedKeys(sects) {
+		sect := sects[prefix]
+		moduleName, sectName := splitSectionName(prefix)
+		sect.Name = sectName
+		module, ok := modules[moduleName]
+		if !ok {
+			module = &Module{Name: moduleName}
+			modules[moduleName] = module
+		}
+		module.Sections = append(module.Sections, sect)
+	}
+	var ms []*Module
+	for _, module := range modules {
+		sort.SliceStable(module.Sections, func(i, j int) bool {
+			return module.Sections[i].Start() < module.Sections[j].Start()
+		})
+		ms = append(ms, module)
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Name < ms[j].Name
+	})
+	return ms
+}
+
+// AddrSymbols returns the linker symbols of the overlay whose value is an
+// address; i.e. excluding the size symbols of sections (e.g.
+// "__RHS2_data_size").
+func (overlay *Overlay) AddrSymbols() []*Symbol {
+	sects := overlay.sections()
+	var syms []*Symbol
+	for _, s := range overlay.Symbols {
+		if prefix, suffix, ok := splitLinkerSymbol(s.Name); ok && suffix == suffixSize {
+			if _, ok := sects[prefix]; ok {
+				continue
+			}
+		}
+		syms = append(syms, s)
+	}
+	return syms
+}
+
+// ExportedFuncs returns the functions recorded by the parser which are visible
+// outside of their source file.
+//
+// Note, the SYM does not record the storage class of functions. Functions
+// without a linker symbol of the same linkage name are presumed static, unless
+// there are no linker symbols.
+func (p *Parser) ExportedFuncs() map[*c.FuncDecl]bool {
+	overlays := append([]*Overlay{p.Overlay}, p.Overlays...)
+	var names map[string]bool
+	for _, overlay := range overlays {
+		for _, s := range overlay.Symbols {
+			if names == nil {
+				names = make(map[string]bool)
+			}
+			names[s.Name] = true
+		}
+	}
+	exported := make(map[*c.FuncDecl]bool)
+	for _, overlay := range overlays {
+		for _, f := range overlay.Funcs {
+			name := f.LinkageName
+			if len(name) == 0 {
+				name = f.Name
+			}
+			if names == nil || names[name] {
+				exported[f] = true
+			}
+		}
+	}
+	return exported
+}
+
+// sections returns the sections of the overlay described by linker symbols,
+// mapping from section prefix to section.
+//
+// Note, a section is only recognized if described by at least two linker
+// symbols (e.g. "text_org" and "text_size"), as plain labels may share a
+// suffix by chance (e.g. "heap_size").
+func (overlay *Overlay) sections() map[string]*Section {
+	sects := make(map[string]*Section)
+	props := make(map[string]map[string]bool)
+	for _, s := range overlay.Symbols {
+		prefix, suffix, ok := splitLinkerSymbol(s.Name)
+		if !ok {
+			continue
+		}
+		sect, ok := sects[prefix]
+		if !ok {
+			sect = &Section{Name: prefix}
+			sects[prefix] = sect
+			props[prefix] = make(map[string]bool)
+		}
+		props[prefix][suffix] = true
+		switch suffix {
+		case suffixOrg:
+			sect.Org = s.Addr
+		case suffixOrgEnd:
+			sect.OrgEnd = s.Addr
+		case suffixObj:
+			sect.Obj = s.Addr
+		case suffixObjEnd:
+			sect.ObjEnd = s.Addr
+		case suffixSize:
+			sect.Size = s.Addr
+		}
+	}
+	for prefix := range sects {
+		if len(props[prefix]) < 2 {
+			delete(sects, prefix)
+		}
+	}
+	return sects
+}
+
+// sortedKeys returns the keys of the given map in sorted order.
+func sortedKeys(m map[string]*Section) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitLinkerSymbol splits the given linker symbol name into section prefix and
+// property suffix.
+func splitLinkerSymbol(name string) (prefix, suffix string, ok bool) {
+	for _, suffix := range []string{suffixOrgEnd, suffixOrg, suffixObjEnd, suffixObj, suffixSize} {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			return strings.TrimSuffix(name, suffix), suffix, true
+		}
+	}
+	return "", "", false
+}
+
+// splitSectionName splits the given section prefix of a linker symbol into
+// module name and section name.
+//
+//	__RHS2_data    ->  RHS2, data
+//	text           ->  "", text
+func splitSectionName(prefix string) (module, sect string) {
+	if !strings.HasPrefix(prefix, "__") {
+		return "", prefix
+	}
+	rest := prefix[len("__"):]
+	pos := strings.LastIndex(rest, "_")
+	if pos <= 0 || pos == len(rest)-1 {
+		return "", rest
+	}
+	return rest[:pos], rest[pos+1:]
+}
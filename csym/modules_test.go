@@ -0,0 +1,74 @@
+package csym_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sanctuary/sym/csym"
+)
+
+func TestModules(t *testing.T) {
+	golden := []struct {
+		syms []*csym.Symbol
+		want []string
+	}{
+		// Sections of object modules.
+		{
+			syms: []*csym.Symbol{
+				{Addr: 0x80010000, Name: "__RHS2_text_org"},
+				{Addr: 0x80010400, Name: "__RHS2_text_orgend"},
+				{Addr: 0x400, Name: "__RHS2_text_size"},
+				{Addr: 0x80090000, Name: "__RHS2_data_obj"},
+				{Addr: 0x80090100, Name: "__RHS2_data_objend"},
+			},
+			want: []string{"RHS2: text [80010000, 80010400)", "RHS2: data [80090000, 80090100)"},
+		},
+		// Sections of the executable.
+		{
+			syms: []*csym.Symbol{
+				{Addr: 0x80010000, Name: "text_org"},
+				{Addr: 0x1000, Name: "text_size"},
+			},
+			want: []string{": text [80010000, 80011000)"},
+		},
+		// Plain labels sharing a suffix by chance.
+		{
+			syms: []*csym.Symbol{
+				{Addr: 0x80080000, Name: "heap_size"},
+				{Addr: 0x80080010, Name: "stack_org"},
+			},
+			want: nil,
+		},
+	}
+	for i, g := range golden {
+		p := csym.NewParser()
+		p.Symbols = g.syms
+		var got []string
+		for _, module := range p.Modules() {
+			for _, sect := range module.Sections {
+				got = append(got, fmt.Sprintf("%s: %s [%08X, %08X)", module.Name, sect.Name, sect.Start(), sect.End()))
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(g.want) {
+			t.Errorf("i=%d: modules mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+func TestAddrSymbols(t *testing.T) {
+	p := csym.NewParser()
+	p.Symbols = []*csym.Symbol{
+		{Addr: 0x80010000, Name: "text_org"},
+		{Addr: 0x1000, Name: "text_size"},
+		{Addr: 0x80080000, Name: "heap_size"},
+		{Addr: 0x80010010, Name: "main"},
+	}
+	var got []string
+	for _, s := range p.AddrSymbols() {
+		got = append(got, s.Name)
+	}
+	want := []string{"text_org", "heap_size", "main"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("address symbols mismatch; expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,211 @@
+package csym
+
+import (
+	"fmt"
+
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// Tag suffixes of named anonymous types.
+const (
+	structSuffix = "_s"
+	unionSuffix  = "_u"
+	enumSuffix   = "_e"
+)
+
+// NameFakeTags replaces the compiler-generated tags of anonymous structs,
+// unions and enums (e.g. "_12fake") with names derived from their context. The
+// name is based on, in order of precedence:
+//
+//  1. the type definition of the type.
+//
+//     typedef struct _3fake PLAYER;      ->  struct PLAYER_s
+//
+//  2. the struct or union field using the type.
+//
+//     struct foo { union _4fake bar; }   ->  union foo_bar_u
+//
+//  3. the global variable using the type.
+//
+//     enum _5fake state;                 ->  enum state_e
+//
+//  4. a hash of the contents of the type.
+//
+//     struct _6fake                      ->  struct anon_1F2E3D4C5B6A7988_s
+//
+// The names only depend on the contents of the SYM file, and are thus stable
+// across builds.
+func (p *Parser) NameFakeTags() {
+	// Anonymous types in order of occurrence in SYM file.
+	var fakes []c.Type
+	for _, tag := range p.StructTags {
+		if c.IsFakeTag(tag) {
+			fakes = append(fakes, p.Structs[tag])
+		}
+	}
+	for _, tag := range p.UnionTags {
+		if c.IsFakeTag(tag) {
+			fakes = append(fakes, p.Unions[tag])
+		}
+	}
+	for _, tag := range p.EnumTags {
+		if c.IsFakeTag(tag) {
+			fakes = append(fakes, p.Enums[tag])
+		}
+	}
+	if len(fakes) == 0 {
+		return
+	}
+	n := &fakeNamer{
+		fakes: make(map[c.Type]bool),
+		names: make(map[c.Type]string),
+	}
+	for _, t := range fakes {
+		n.fakes[t] = true
+	}
+	// 1. Type definitions.
+	for _, def := range p.Typedefs {
+		if def, ok := def.(*c.VarDecl); ok {
+			n.name(def.Type, def.Name)
+		}
+	}
+	// 2. Fields.
+	n.nameFields(p)
+	// 3. Global variables.
+	for _, overlay := range append([]*Overlay{p.Overlay}, p.Overlays...) {
+		for _, v := range overlay.Vars {
+			n.name(elemType(v.Type), v.Name)
+		}
+	}
+	// Fields of types named after global variables.
+	n.nameFields(p)
+	// 4. Hash of contents.
+	cmp := c.Comparer{IgnoreFakeTags: true}
+	for _, t := range fakes {
+		n.name(t, fmt.Sprintf("anon_%016X", cmp.Hash(t)))
+	}
+
+	// Rename tags.
+	structTags := make(map[string]bool)
+	for _, tag := range p.StructTags {
+		structTags[tag] = true
+	}
+	unionTags := make(map[string]bool)
+	for _, tag := range p.UnionTags {
+		unionTags[tag] = true
+	}
+	enumTags := make(map[string]bool)
+	for _, tag := range p.EnumTags {
+		enumTags[tag] = true
+	}
+	for i, tag := range p.StructTags {
+		t := p.Structs[tag]
+		if !n.fakes[t] {
+			continue
+		}
+		newTag := uniqueTag(n.names[t]+structSuffix, structTags)
+		delete(p.Structs, tag)
+		p.Structs[newTag] = t
+		p.StructTags[i] = newTag
+		t.Tag = newTag
+	}
+	for i, tag := range p.UnionTags {
+		t := p.Unions[tag]
+		if !n.fakes[t] {
+			continue
+		}
+		newTag := uniqueTag(n.names[t]+unionSuffix, unionTags)
+		delete(p.Unions, tag)
+		p.Unions[newTag] = t
+		p.UnionTags[i] = newTag
+		t.Tag = newTag
+	}
+	for i, tag := range p.EnumTags {
+		t := p.Enums[tag]
+		if !n.fakes[t] {
+			continue
+		}
+		newTag := uniqueTag(n.names[t]+enumSuffix, enumTags)
+		delete(p.Enums, tag)
+		p.Enums[newTag] = t
+		p.EnumTags[i] = newTag
+		t.Tag = newTag
+	}
+}
+
+// fakeNamer tracks the names of anonymous types.
+type fakeNamer struct {
+	// fakes is the set of anonymous types.
+	fakes map[c.Type]bool
+	// names maps from anonymous type to name.
+	names map[c.Type]string
+}
+
+// name names the given type, if anonymous and not yet named. The return value
+// reports whether the type was named.
+func (n *fakeNamer) name(t c.Type, name string) bool {
+	if !n.fakes[t] {
+		return false
+	}
+	if _, ok := n.names[t]; ok {
+		return false
+	}
+	n.names[t] = name
+	return true
+}
+
+// tag returns the (possibly not yet assigned) tag of the given struct or union,
+// and reports whether it is known.
+func (n *fakeNamer) tag(t c.Type, tag string) (string, bool) {
+	if !n.fakes[t] {
+		return tag, true
+	}
+	name, ok := n.names[t]
+	return name, ok
+}
+
+// nameFields names anonymous types after the fields using them, until no more
+// types may be named.
+func (n *fakeNamer) nameFields(p *Parser) {
+	for {
+		progress := false
+		for _, tag := range p.StructTags {
+			t := p.Structs[tag]
+			if parent, ok := n.tag(t, t.Tag); ok {
+				for _, field := range t.Fields {
+					if n.name(elemType(field.Type), parent+"_"+field.Name) {
+						progress = true
+					}
+				}
+			}
+		}
+		for _, tag := range p.UnionTags {
+			t := p.Unions[tag]
+			if parent, ok := n.tag(t, t.Tag); ok {
+				for _, field := range t.Fields {
+					if n.name(elemType(field.Type), parent+"_"+field.Name) {
+						progress = true
+					}
+				}
+			}
+		}
+		if !progress {
+			return
+		}
+	}
+}
+
+// elemType returns the element type of the given type, after stripping
+// pointers and arrays.
+func elemType(t c.Type) c.Type {
+	for {
+		switch tt := t.(type) {
+		case *c.PointerType:
+			t = tt.Elem
+		case *c.ArrayType:
+			t = tt.Elem
+		default:
+			return t
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package csym_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sanctuary/sym"
+)
+
+func TestNameFakeTags(t *testing.T) {
+	syms := []*sym.Symbol{
+		// typedef struct _0fake { int x; } PLAYER;
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 4, "_0fake"),
+		def(0, sym.ClassMOS, sym.Type(sym.BaseInt), 4, "x"),
+		eos(),
+		def2(0, sym.ClassTPDEF, sym.Type(sym.BaseStruct), 4, nil, "_0fake", "PLAYER"),
+		// union _1fake { int i; short s; };
+		def(0, sym.ClassUNTAG, sym.Type(sym.BaseUnion), 4, "_1fake"),
+		def(0, sym.ClassMOU, sym.Type(sym.BaseInt), 4, "i"),
+		def(0, sym.ClassMOU, sym.Type(sym.BaseShort), 2, "s"),
+		eos(),
+		// struct foo { union _1fake bar[2]; };
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 8, "foo"),
+		def2(0, sym.ClassMOS, tAry|sym.Type(sym.BaseUnion), 8, []uint32{2}, "_1fake", "bar"),
+		eos(),
+		// enum _2fake { A, B };
+		def(0, sym.ClassENTAG, sym.Type(sym.BaseEnum), 4, "_2fake"),
+		def(0, sym.ClassMOE, sym.Type(sym.BaseInt), 4, "A"),
+		def(1, sym.ClassMOE, sym.Type(sym.BaseInt), 4, "B"),
+		eos(),
+		// struct _3fake { char c; };
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 1, "_3fake"),
+		def(0, sym.ClassMOS, sym.Type(sym.BaseChar), 1, "c"),
+		eos(),
+		// enum _2fake state;
+		def2(0x80010000, sym.ClassEXT, sym.Type(sym.BaseEnum), 4, nil, "_2fake", "state"),
+	}
+	p := parse(t, syms)
+	player, bar, state, anon := p.Structs["_0fake"], p.Unions["_1fake"], p.Enums["_2fake"], p.Structs["_3fake"]
+	p.NameFakeTags()
+	golden := []struct {
+		got  string
+		want string
+	}{
+		// Named after type definition.
+		{got: player.Tag, want: `^PLAYER_s$`},
+		// Named after field of array type.
+		{got: bar.Tag, want: `^foo_bar_u$`},
+		// Named after global variable.
+		{got: state.Tag, want: `^state_e$`},
+		// Named after hash of contents.
+		{got: anon.Tag, want: `^anon_[0-9A-F]{16}_s$`},
+	}
+	for i, g := range golden {
+		if !regexp.MustCompile(g.want).MatchString(g.got) {
+			t.Errorf("i=%d: tag mismatch; expected %q, got %q", i, g.want, g.got)
+		}
+	}
+	// Type definitions refer to the renamed tag.
+	if got, want := p.Types["PLAYER"].Def(), "typedef struct PLAYER_s PLAYER"; got != want {
+		t.Errorf("type definition mismatch; expected %q, got %q", want, got)
+	}
+	if _, ok := p.Structs["PLAYER_s"]; !ok {
+		t.Errorf("unable to locate renamed struct %q", "PLAYER_s")
+	}
+}
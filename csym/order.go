@@ -0,0 +1,232 @@
+package csym
+
+import (
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// OrderedTypes returns the type definitions recorded by the parser in
+// dependency order; i.e. each type is defined before it is used by value, and
+// each typedef is defined right after the types it names. Forward declarations
+// of struct and union tags (*c.TagDecl) are inserted where a tag is referenced
+// through a pointer before its definition, thus breaking pointer cycles.
+func (p *Parser) OrderedTypes() []c.Type {
+	var roots []c.Type
+	for _, tag := range p.EnumTags {
+		roots = append(roots, p.Enums[tag])
+	}
+	for _, tag := range p.StructTags {
+		roots = append(roots, p.Structs[tag])
+	}
+	for _, tag := range p.UnionTags {
+		roots = append(roots, p.Unions[tag])
+	}
+	roots = append(roots, p.Typedefs...)
+	return orderTypes(roots)
+}
+
+// ContextTypes returns the type definitions recorded by the parser which are
+// transitively used by the given nodes (e.g. a function declaration), in
+// dependency order.
+func (p *Parser) ContextTypes(nodes ...c.Node) []c.Type {
+	typedefs := make(map[string]c.Type)
+	for _, def := range p.Typedefs {
+		if v, ok := def.(*c.VarDecl); ok {
+			if _, ok := typedefs[v.Name]; !ok {
+				typedefs[v.Name] = v
+			}
+		}
+	}
+	used := make(map[c.Type]bool)
+	for _, node := range nodes {
+		c.Inspect(node, func(n c.Node) bool {
+			switch n := n.(type) {
+			case *c.StructType:
+				used[n] = true
+			case *c.UnionType:
+				used[n] = true
+			case *c.EnumType:
+				used[n] = true
+			case *c.VarDecl:
+				if n.Class == c.Typedef {
+					used[n] = true
+				}
+			case *c.TypedefType:
+				// Note, the underlying type of the type definition is visited
+				// through the type reference.
+				if def, ok := typedefs[n.Name]; ok {
+					used[def] = true
+				}
+			}
+			return true
+		})
+	}
+	var roots []c.Type
+	for _, t := range p.OrderedTypes() {
+		if used[t] {
+			roots = append(roots, t)
+		}
+	}
+	return orderTypes(roots)
+}
+
+// orderTypes returns the given type definitions in dependency order, with
+// forward declarations inserted as needed. Only the given type definitions are
+// output; other types referenced are assumed to be predeclared.
+func orderTypes(defs []c.Type) []c.Type {
+	o := &typeOrder{
+		defs:     make(map[c.Type]bool),
+		state:    make(map[c.Type]visitState),
+		declared: make(map[c.Type]bool),
+		typedefs: make(map[string]c.Type),
+	}
+	for _, def := range defs {
+		o.defs[def] = true
+		if v, ok := def.(*c.VarDecl); ok {
+			if _, ok := o.typedefs[v.Name]; !ok {
+				o.typedefs[v.Name] = v
+			}
+		}
+	}
+	for _, def := range defs {
+		o.visit(def)
+	}
+	return o.types
+}
+
+// visitState specifies the visit state of a type definition during
+// topological sort.
+type visitState uint8
+
+// Visit states.
+const (
+	// Type definition not yet visited.
+	unvisited visitState = iota
+	// Type definition being visited; dependencies are being output.
+	visiting
+	// Type definition output.
+	visited
+)
+
+// typeOrder tracks the state of a topological sort of type definitions.
+type typeOrder struct {
+	// defs is the set of type definitions to output.
+	defs map[c.Type]bool
+	// state maps from type definition to visit state.
+	state map[c.Type]visitState
+	// declared is the set of struct and union tags with forward declarations.
+	declared map[c.Type]bool
+	// typedefs maps from type name to type definition.
+	typedefs map[string]c.Type
+	// stack of type definitions being visited.
+	stack []c.Type
+	// Type definitions and forward declarations in dependency order.
+	types []c.Type
+}
+
+// visit outputs the given type definition after its dependencies.
+func (o *typeOrder) visit(t c.Type) {
+	switch o.state[t] {
+	case visiting:
+		// Dependency cycle; the best we can do is a forward declaration.
+		o.declare(t)
+		return
+	case visited:
+		return
+	}
+	o.state[t] = visiting
+	o.stack = append(o.stack, t)
+	switch t := t.(type) {
+	case *c.StructType:
+		for _, field := range t.Fields {
+			o.complete(field.Type)
+		}
+	case *c.UnionType:
+		for _, field := range t.Fields {
+			o.complete(field.Type)
+		}
+	case *c.VarDecl:
+		// Type definition.
+		o.complete(t.Type)
+	}
+	o.stack = o.stack[:len(o.stack)-1]
+	o.state[t] = visited
+	o.types = append(o.types, t)
+}
+
+// complete outputs the type definitions required to use the given type by
+// value.
+func (o *typeOrder) complete(t c.Type) {
+	switch t := t.(type) {
+	case *c.StructType, *c.UnionType, *c.EnumType:
+		if o.defs[t] {
+			o.visit(t)
+		}
+	case *c.VarDecl:
+		// Type definition; the underlying type must be complete as well.
+		if o.defs[t] {
+			o.visit(t)
+		}
+		o.complete(t.Type)
+	case *c.TypedefType:
+		if def, ok := o.typedefs[t.Name]; ok {
+			o.visit(def)
+		}
+		o.complete(t.Type)
+	case *c.PointerType:
+		o.reference(t.Elem)
+	case *c.ArrayType:
+		o.complete(t.Elem)
+	case *c.FuncType:
+		o.reference(t.RetType)
+		for _, param := range t.Params {
+			o.reference(param.Type)
+		}
+	}
+}
+
+// reference outputs the type definitions and forward declarations required to
+// refer to the given type through a pointer.
+func (o *typeOrder) reference(t c.Type) {
+	switch t := t.(type) {
+	case *c.StructType, *c.UnionType:
+		o.declare(t)
+	case *c.EnumType, *c.VarDecl:
+		// Enums have no dependencies and type definitions are referred to by
+		// name, so output both directly.
+		if o.defs[t] {
+			o.visit(t)
+		}
+	case *c.TypedefType:
+		if def, ok := o.typedefs[t.Name]; ok {
+			o.visit(def)
+		}
+	case *c.PointerType:
+		o.reference(t.Elem)
+	case *c.ArrayType:
+		o.reference(t.Elem)
+	case *c.FuncType:
+		o.reference(t.RetType)
+		for _, param := range t.Params {
+			o.reference(param.Type)
+		}
+	}
+}
+
+// declare outputs a forward declaration of the given struct or union tag,
+// unless already defined or declared.
+func (o *typeOrder) declare(t c.Type) {
+	switch t.(type) {
+	case *c.StructType, *c.UnionType:
+	default:
+		return
+	}
+	if !o.defs[t] || o.state[t] == visited || o.declared[t] {
+		return
+	}
+	// The tag of the innermost struct or union being defined is in scope.
+	if n := len(o.stack); n > 0 && o.stack[n-1] == t {
+		return
+	}
+	o.declared[t] = true
+	o.types = append(o.types, &c.TagDecl{Type: t})
+}
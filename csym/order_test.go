@@ -0,0 +1,100 @@
+package csym_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sanctuary/sym"
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// orderSyms are symbols of types used by value, through pointers and through
+// type definitions.
+var orderSyms = []*sym.Symbol{
+	// struct A { struct B b; struct A *next; };
+	def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 8, "A"),
+	def2(0, sym.ClassMOS, sym.Type(sym.BaseStruct), 4, nil, "B", "b"),
+	def2(4, sym.ClassMOS, tPtr|sym.Type(sym.BaseStruct), 4, nil, "A", "next"),
+	eos(),
+	// struct B { struct C *c; };
+	def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 4, "B"),
+	def2(0, sym.ClassMOS, tPtr|sym.Type(sym.BaseStruct), 4, nil, "C", "c"),
+	eos(),
+	// struct C { BT b; };
+	def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 4, "C"),
+	def2(0, sym.ClassMOS, sym.Type(sym.BaseStruct), 4, nil, "BT", "b"),
+	eos(),
+	// typedef struct B BT;
+	def2(0, sym.ClassTPDEF, sym.Type(sym.BaseStruct), 4, nil, "B", "BT"),
+	// struct D { int x; };
+	def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 4, "D"),
+	def(0, sym.ClassMOS, sym.Type(sym.BaseInt), 4, "x"),
+	eos(),
+}
+
+func TestOrderedTypes(t *testing.T) {
+	p := parse(t, orderSyms)
+	want := []string{
+		"declare struct C",
+		"struct B",
+		"struct A",
+		"typedef struct B BT",
+		"struct C",
+		"struct D",
+	}
+	got := typeKeys(p.OrderedTypes(), "__vtbl_ptr_type")
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("type order mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestContextTypes(t *testing.T) {
+	p := parse(t, orderSyms)
+	golden := []struct {
+		node c.Node
+		want []string
+	}{
+		// Pointer to type only requires the pointed-to type definition and its
+		// dependencies.
+		{
+			node: &c.PointerType{Elem: p.Structs["C"]},
+			want: []string{"declare struct C", "struct B", "typedef struct B BT", "struct C"},
+		},
+		// Unrelated types are left out.
+		{
+			node: p.Structs["D"],
+			want: []string{"struct D"},
+		},
+	}
+	for i, g := range golden {
+		got := typeKeys(p.ContextTypes(g.node))
+		if strings.Join(got, "; ") != strings.Join(g.want, "; ") {
+			t.Errorf("i=%d: context types mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
+
+// typeKeys returns a short description of each of the given type definitions
+// and forward declarations, except for the given predeclared tags.
+func typeKeys(ts []c.Type, skip ...string) []string {
+	var keys []string
+loop:
+	for _, t := range ts {
+		if st, ok := t.(*c.StructType); ok {
+			for _, tag := range skip {
+				if st.Tag == tag {
+					continue loop
+				}
+			}
+		}
+		switch t := t.(type) {
+		case *c.TagDecl:
+			keys = append(keys, "declare "+t.String())
+		case *c.VarDecl:
+			keys = append(keys, t.Def())
+		default:
+			keys = append(keys, t.String())
+		}
+	}
+	return keys
+}
@@ -2,6 +2,7 @@
 package csym
 
 import (
+	"github.com/sanctuary/sym"
 	"github.com/sanctuary/sym/csym/c"
 )
 
@@ -27,6 +28,17 @@ type Parser struct {
 	Typedefs []c.Type
 	// Tracks unique enum member names.
 	enumMembers map[string]bool
+	// typedefRefs maps from type name to type definition reference.
+	typedefRefs map[string]*c.TypedefType
+	// typedefSymTypes maps from type name to SYM type of type definition.
+	typedefSymTypes map[string]sym.Type
+	// typedefTags maps from type name to tag of the underlying type of type
+	// definition.
+	typedefTags map[string]string
+
+	// Expand type definitions; i.e. refer to the underlying type rather than the
+	// type name of type definitions.
+	ExpandTypedefs bool
 
 	// Declarations.
 	*Overlay // default binary
@@ -38,6 +50,13 @@ type Parser struct {
 
 	// Current overlay.
 	curOverlay *Overlay
+	// Source file of the most recently parsed function.
+	lastPath string
+	// Global variables parsed since the most recently parsed function.
+	pendingVars []*c.VarDecl
+	// varSymPaths maps from global variable to the source files of adjacent
+	// functions in SYM order.
+	varSymPaths map[*c.VarDecl]*symPaths
 }
 
 // NewParser returns a new parser.
@@ -47,14 +66,18 @@ func NewParser() *Parser {
 		funcNames: make(map[string]*c.FuncDecl),
 	}
 	return &Parser{
-		Structs:     make(map[string]*c.StructType),
-		Unions:      make(map[string]*c.UnionType),
-		Enums:       make(map[string]*c.EnumType),
-		Types:       make(map[string]c.Type),
-		enumMembers: make(map[string]bool),
-		Overlay:     overlay,
-		overlayIDs:  make(map[uint32]*Overlay),
-		curOverlay:  overlay,
+		Structs:         make(map[string]*c.StructType),
+		Unions:          make(map[string]*c.UnionType),
+		Enums:           make(map[string]*c.EnumType),
+		Types:           make(map[string]c.Type),
+		enumMembers:     make(map[string]bool),
+		typedefRefs:     make(map[string]*c.TypedefType),
+		typedefSymTypes: make(map[string]sym.Type),
+		typedefTags:     make(map[string]string),
+		Overlay:         overlay,
+		overlayIDs:      make(map[uint32]*Overlay),
+		curOverlay:      overlay,
+		varSymPaths:     make(map[*c.VarDecl]*symPaths),
 	}
 }
 
@@ -3,12 +3,14 @@ package csym
 import (
 	"fmt"
 
+	"github.com/pkg/errors"
 	"github.com/sanctuary/sym"
 	"github.com/sanctuary/sym/csym/c"
+	"github.com/sanctuary/sym/demangle"
 )
 
 // ParseDecls parses the symbols into the equivalent C declarations.
-func (p *Parser) ParseDecls(syms []*sym.Symbol) {
+func (p *Parser) ParseDecls(syms []*sym.Symbol) error {
 	for i := 0; i < len(syms); i++ {
 		s := syms[i]
 		switch body := s.Body.(type) {
@@ -20,7 +22,10 @@ func (p *Parser) ParseDecls(syms []*sym.Symbol) {
 			n := p.parseLineNumbers(s.Hdr.Value, body, syms[i+1:])
 			i += n
 		case *sym.FuncStart:
-			n := p.parseFunc(s.Hdr.Value, body, syms[i+1:])
+			n, err := p.parseFunc(s.Hdr.Value, body, syms[i+1:])
+			if err != nil {
+				return errors.WithStack(err)
+			}
 			i += n
 		case *sym.Def:
 			switch body.Class {
@@ -49,11 +54,19 @@ func (p *Parser) ParseDecls(syms []*sym.Symbol) {
 			if !ok {
 				panic(fmt.Errorf("unable to locate overlay with ID %x", s.Hdr.Value))
 			}
+			if overlay != p.curOverlay {
+				p.resetFuncPath()
+			}
 			p.curOverlay = overlay
 		default:
 			panic(fmt.Sprintf("support for symbol type %T not yet implemented", body))
 		}
 	}
+	p.recoverPrototypes()
+	p.inferVarPaths()
+	p.inferDeclEnumSizes()
+	p.recoverClassMembers()
+	return nil
 }
 
 // parseSymbol parses a symbol and its associated address.
@@ -133,67 +146,75 @@ func (p *Parser) parseLineNumbers(addr uint32, body *sym.SetSLD2, syms []*sym.Sy
 }
 
 // parseFunc parses a function sequence of symbols.
-func (p *Parser) parseFunc(addr uint32, body *sym.FuncStart, syms []*sym.Symbol) (n int) {
+func (p *Parser) parseFunc(addr uint32, body *sym.FuncStart, syms []*sym.Symbol) (n int, err error) {
 	f, funcType := findFunc(p, body.Name, addr)
+	p.trackFuncPath(body.Path)
 	// Ignore duplicate function (already parsed).
 	if f.LineStart != 0 {
 		for n = 0; n < len(syms); n++ {
 			if _, ok := syms[n].Body.(*sym.FuncEnd); ok {
-				return n + 1
+				return n + 1, nil
 			}
 		}
 	}
 	f.Path = body.Path
 	// Parse function declaration.
 	f.LineStart = body.Line
-	curLine := Line{
-		Path: body.Path,
-		Line: body.Line,
-	}
+	f.Frame = parseFrame(body)
 	line := &Line{
 		Addr: addr,
-		Path: curLine.Path,
-		Line: curLine.Line,
+		Path: body.Path,
+		Line: body.Line,
 	}
 	p.curOverlay.Lines = append(p.curOverlay.Lines, line)
+	// blockLine returns the absolute line number of the given line number
+	// relative to the start of the function.
+	blockLine := func(line uint32) uint32 {
+		return f.LineStart + line - 1
+	}
 	var blocks blockStack
 	var curBlock *c.Block
 	for n = 0; n < len(syms); n++ {
 		s := syms[n]
 		switch body := s.Body.(type) {
 		case *sym.FuncEnd:
-			f.LineEnd = body.Line
-			return n + 1
+			f.LineEnd = blockLine(body.Line)
+			return n + 1, nil
 		case *sym.BlockStart:
+			block := &c.Block{
+				Addr:      s.Hdr.Value,
+				LineStart: blockLine(body.Line),
+			}
 			if curBlock != nil {
+				curBlock.Blocks = append(curBlock.Blocks, block)
 				blocks.push(curBlock)
+			} else {
+				f.Blocks = append(f.Blocks, block)
 			}
-			block := &c.Block{
-				LineStart: body.Line,
-			}
-			f.Blocks = append(f.Blocks, block)
 			curBlock = block
-			curLine.Line += body.Line - 1
 			line := &Line{
 				Addr: s.Hdr.Value,
-				Path: curLine.Path,
-				Line: curLine.Line,
+				Path: f.Path,
+				Line: block.LineStart,
 			}
 			p.curOverlay.Lines = append(p.curOverlay.Lines, line)
 		case *sym.BlockEnd:
-			curBlock.LineEnd = body.Line
-			if !blocks.empty() {
-				curBlock = blocks.pop()
-			} else {
-				curBlock = nil
+			if curBlock == nil {
+				return 0, errors.Errorf("unmatched block end at address 0x%08X of function %q", s.Hdr.Value, f.Name)
 			}
-			curLine.Line += body.Line - 1
+			curBlock.EndAddr = s.Hdr.Value
+			curBlock.LineEnd = blockLine(body.Line)
 			line := &Line{
 				Addr: s.Hdr.Value,
-				Path: curLine.Path,
-				Line: curLine.Line,
+				Path: f.Path,
+				Line: curBlock.LineEnd,
 			}
 			p.curOverlay.Lines = append(p.curOverlay.Lines, line)
+			if !blocks.empty() {
+				curBlock = blocks.pop()
+			} else {
+				curBlock = nil
+			}
 		case *sym.Def:
 			t := p.parseType(body.Type, nil, "")
 			v := p.parseLocalDecl(s.Hdr.Value, body.Size, body.Class, t, body.Name)
@@ -202,6 +223,9 @@ func (p *Parser) parseFunc(addr uint32, body *sym.FuncStart, syms []*sym.Symbol)
 			} else {
 				addParam(funcType, v)
 			}
+			if isStackVar(body.Class) {
+				addStackVar(f.Frame, v)
+			}
 		case *sym.Def2:
 			t := p.parseType(body.Type, body.Dims, body.Tag)
 			v := p.parseLocalDecl(s.Hdr.Value, body.Size, body.Class, t, body.Name)
@@ -210,18 +234,47 @@ func (p *Parser) parseFunc(addr uint32, body *sym.FuncStart, syms []*sym.Symbol)
 			} else {
 				addParam(funcType, v)
 			}
+			if isStackVar(body.Class) {
+				addStackVar(f.Frame, v)
+			}
 		default:
 			panic(fmt.Errorf("support for symbol type %T not yet implemented", body))
 		}
 	}
-	panic("unreachable")
+	return 0, errors.Errorf("missing function end of function %q", f.Name)
+}
+
+// parseFrame parses the stack frame layout of the given function start
+// symbol.
+func parseFrame(body *sym.FuncStart) *c.Frame {
+	frame := &c.Frame{
+		FP:     c.Reg(body.FP),
+		Size:   body.FSize,
+		RetReg: c.Reg(body.RetReg),
+	}
+	// The mask offset specifies the stack slot of the highest numbered saved
+	// register, relative to the top of the frame; lower numbered registers are
+	// saved in consecutive stack slots below.
+	offset := int32(body.FSize) + body.MaskOffset
+	for reg := 31; reg >= 0; reg-- {
+		if body.Mask&(1<<uint(reg)) == 0 {
+			continue
+		}
+		saved := &c.SavedReg{
+			Reg:    c.Reg(reg),
+			Offset: offset,
+		}
+		frame.Saved = append(frame.Saved, saved)
+		offset -= 4
+	}
+	return frame
 }
 
 // parseLocalDecl parses a local declaration symbol.
 func (p *Parser) parseLocalDecl(addr, size uint32, class sym.Class, t c.Type, name string) *c.VarDecl {
 	name = validName(name)
 	v := &c.VarDecl{
-		Addr:  addr,
+		Loc:   parseLocation(addr, class),
 		Size:  size,
 		Class: parseClass(class),
 		Var: c.Var{
@@ -241,15 +294,27 @@ func (p *Parser) parseLocalDecl(addr, size uint32, class sym.Class, t c.Type, na
 
 // parseGlobalDecl parses a global declaration symbol.
 func (p *Parser) parseGlobalDecl(addr, size uint32, class sym.Class, t c.Type, name string) {
+	// Keep the linkage name of C++ symbols and renamed symbols.
+	var linkageName, demangled string
+	symName := name
+	if s, err := demangle.Parse(name); err == nil {
+		linkageName = symName
+		demangled = s.String()
+	}
 	name = validName(name)
 	if _, ok := t.(*c.FuncType); ok {
 		// Make name unique if already present.
 		if _, ok := p.curOverlay.funcNames[name]; ok {
 			name = UniqueName(name, addr)
 		}
+		if name != symName {
+			linkageName = symName
+		}
 		f := &c.FuncDecl{
-			Addr: addr,
-			Size: size,
+			Addr:        addr,
+			Size:        size,
+			LinkageName: linkageName,
+			Demangled:   demangled,
 			Var: c.Var{
 				Type: t,
 				Name: name,
@@ -263,10 +328,15 @@ func (p *Parser) parseGlobalDecl(addr, size uint32, class sym.Class, t c.Type, n
 	if _, ok := p.curOverlay.varNames[name]; ok {
 		name = UniqueName(name, addr)
 	}
+	if name != symName {
+		linkageName = symName
+	}
 	v := &c.VarDecl{
-		Addr:  addr,
-		Size:  size,
-		Class: parseClass(class),
+		Loc:         &c.StaticLoc{Addr: addr},
+		Size:        size,
+		Class:       parseClass(class),
+		LinkageName: linkageName,
+		Demangled:   demangled,
 		Var: c.Var{
 			Type: t,
 			Name: name,
@@ -274,6 +344,7 @@ func (p *Parser) parseGlobalDecl(addr, size uint32, class sym.Class, t c.Type, n
 	}
 	p.curOverlay.Vars = append(p.curOverlay.Vars, v)
 	p.curOverlay.varNames[name] = v
+	p.trackVar(v)
 }
 
 // parseOverlay parses an overlay symbol.
@@ -341,6 +412,19 @@ func parseClass(class sym.Class) c.StorageClass {
 	}
 }
 
+// parseLocation returns the storage location of a local declaration symbol
+// based on its value and symbol class.
+func parseLocation(value uint32, class sym.Class) c.Location {
+	switch class {
+	case sym.ClassREG, sym.ClassREGPARM:
+		return &c.RegLoc{Reg: c.Reg(value)}
+	case sym.ClassAUTO, sym.ClassARG:
+		return &c.StackLoc{Offset: int32(value)}
+	default:
+		return &c.StaticLoc{Addr: value}
+	}
+}
+
 // blockStack is a stack of blocks.
 type blockStack []*c.Block
 
@@ -375,11 +459,34 @@ func addLocal(block *c.Block, local *c.VarDecl) {
 	block.Locals = append(block.Locals, local)
 }
 
+// isStackVar reports whether variables of the given symbol class are stack
+// resident.
+func isStackVar(class sym.Class) bool {
+	return class == sym.ClassAUTO || class == sym.ClassARG
+}
+
+// addStackVar adds the stack-resident variable to the stack frame if not
+// already present.
+func addStackVar(frame *c.Frame, v *c.VarDecl) {
+	for _, w := range frame.Vars {
+		if w.Name == v.Name {
+			return
+		}
+	}
+	frame.Vars = append(frame.Vars, v)
+}
+
 // addParam adds the function parameter to the function type if not already
 // present.
 func addParam(t *c.FuncType, param *c.VarDecl) {
 	for _, p := range t.Params {
 		if p.Name == param.Name {
+			// K&R function definitions declare the parameter twice; first with
+			// the promoted type as passed (e.g. int), and then with the declared
+			// type (e.g. char).
+			if isPromoted(p.Type, param.Type) {
+				p.Type = param.Type
+			}
 			return
 		}
 	}
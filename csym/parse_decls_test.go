@@ -0,0 +1,86 @@
+package csym_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym"
+	"github.com/sanctuary/sym/csym"
+)
+
+func TestParseFuncLines(t *testing.T) {
+	const path = `C:\GAME\MAIN.C`
+	syms := []*sym.Symbol{
+		def(0x80010000, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "main"),
+		// Line numbers of blocks and function end are relative to the
+		// function start line.
+		newSym(0x80010000, &sym.FuncStart{FP: 29, FSize: 8, RetReg: 31, Line: 100, Path: path, Name: "main"}),
+		newSym(0x80010008, &sym.BlockStart{Line: 1}),
+		newSym(0x80010010, &sym.BlockStart{Line: 3}),
+		newSym(0x80010018, &sym.BlockEnd{Line: 5}),
+		newSym(0x80010020, &sym.BlockEnd{Line: 7}),
+		newSym(0x80010028, &sym.FuncEnd{Line: 8}),
+	}
+	p := parse(t, syms)
+	if len(p.Funcs) != 1 {
+		t.Fatalf("number of functions mismatch; expected 1, got %d", len(p.Funcs))
+	}
+	f := p.Funcs[0]
+	if f.LineStart != 100 || f.LineEnd != 107 {
+		t.Errorf("function lines mismatch; expected 100-107, got %d-%d", f.LineStart, f.LineEnd)
+	}
+	if len(f.Blocks) != 1 || len(f.Blocks[0].Blocks) != 1 {
+		t.Fatalf("block tree mismatch; expected one nested block")
+	}
+	body, inner := f.Blocks[0], f.Blocks[0].Blocks[0]
+	if body.LineStart != 100 || body.LineEnd != 106 {
+		t.Errorf("body block lines mismatch; expected 100-106, got %d-%d", body.LineStart, body.LineEnd)
+	}
+	if inner.LineStart != 102 || inner.LineEnd != 104 {
+		t.Errorf("nested block lines mismatch; expected 102-104, got %d-%d", inner.LineStart, inner.LineEnd)
+	}
+}
+
+func TestParseFuncUnmatchedBlockEnd(t *testing.T) {
+	syms := []*sym.Symbol{
+		def(0x80010000, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "main"),
+		newSym(0x80010000, &sym.FuncStart{FP: 29, FSize: 8, RetReg: 31, Line: 10, Path: "main.c", Name: "main"}),
+		newSym(0x80010008, &sym.BlockEnd{Line: 2}),
+		newSym(0x80010010, &sym.FuncEnd{Line: 3}),
+	}
+	p := csym.NewParser()
+	p.ParseTypes(syms)
+	if err := p.ParseDecls(syms); err == nil {
+		t.Errorf("expected error for unmatched block end")
+	}
+}
+
+func TestParseGlobalDeclLinkageName(t *testing.T) {
+	syms := []*sym.Symbol{
+		def(0x80010000, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "main"),
+		def(0x80010100, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "init.part"),
+		def(0x80010200, sym.ClassSTAT, tFcn|sym.Type(sym.BaseVoid), 0, "main"),
+		def(0x80010300, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "Add__5CListi"),
+	}
+	p := parse(t, syms)
+	golden := []struct {
+		name, linkageName string
+	}{
+		// Valid and unique name.
+		{name: "main", linkageName: ""},
+		// Invalid identifier.
+		{name: "init_part", linkageName: "init.part"},
+		// Duplicate name.
+		{name: "main_addr_80010200", linkageName: "main"},
+		// Mangled name.
+		{name: "Add__5CListi", linkageName: "Add__5CListi"},
+	}
+	if len(p.Funcs) != len(golden) {
+		t.Fatalf("function count mismatch; expected %d, got %d", len(golden), len(p.Funcs))
+	}
+	for i, g := range golden {
+		f := p.Funcs[i]
+		if f.Name != g.name || f.LinkageName != g.linkageName {
+			t.Errorf("i=%d: function name mismatch; expected %q (linkage name %q), got %q (linkage name %q)", i, g.name, g.linkageName, f.Name, f.LinkageName)
+		}
+	}
+}
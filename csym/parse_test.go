@@ -0,0 +1,46 @@
+package csym_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym"
+	"github.com/sanctuary/sym/csym"
+)
+
+// Type modifiers of SYM types, shifted into place for the first (innermost)
+// modifier.
+const (
+	tPtr = sym.Type(sym.ModPointer) << 4
+	tFcn = sym.Type(sym.ModFunction) << 4
+	tAry = sym.Type(sym.ModArray) << 4
+)
+
+// newSym returns a new symbol of the given value and body.
+func newSym(value uint32, body sym.SymbolBody) *sym.Symbol {
+	return &sym.Symbol{Hdr: &sym.SymbolHeader{Value: value}, Body: body}
+}
+
+// def returns a new definition symbol.
+func def(value uint32, class sym.Class, t sym.Type, size uint32, name string) *sym.Symbol {
+	return newSym(value, &sym.Def{Class: class, Type: t, Size: size, Name: name})
+}
+
+// def2 returns a new definition symbol with dimensions and tag.
+func def2(value uint32, class sym.Class, t sym.Type, size uint32, dims []uint32, tag, name string) *sym.Symbol {
+	return newSym(value, &sym.Def2{Class: class, Type: t, Size: size, Dims: dims, Tag: tag, Name: name})
+}
+
+// eos returns a new end of structure symbol.
+func eos() *sym.Symbol {
+	return def2(0, sym.ClassEOS, 0, 0, nil, "", "")
+}
+
+// parse parses the given symbols into C types and declarations.
+func parse(t *testing.T, syms []*sym.Symbol) *csym.Parser {
+	p := csym.NewParser()
+	p.ParseTypes(syms)
+	if err := p.ParseDecls(syms); err != nil {
+		t.Fatalf("unable to parse declarations; %+v", err)
+	}
+	return p
+}
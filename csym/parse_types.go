@@ -38,6 +38,8 @@ func (p *Parser) ParseTypes(syms []*sym.Symbol) {
 			}
 		}
 	}
+	p.inferFieldEnumSizes()
+	p.recoverClassTypes()
 }
 
 // initTaggedTypes adds scaffolding types for structs, unions and enums.
@@ -55,10 +57,10 @@ func (p *Parser) initTaggedTypes(syms []*sym.Symbol) {
 	// Add scaffolding types for structs, unions and enums, so they may be
 	// referrenced before defined.
 	vtblPtrType := &c.StructType{
-		Tag: "__vtbl_ptr_type",
+		Tag: vtblPtrTag,
 	}
-	p.Structs["__vtbl_ptr_type"] = vtblPtrType
-	p.StructTags = append(p.StructTags, "__vtbl_ptr_type")
+	p.Structs[vtblPtrTag] = vtblPtrType
+	p.StructTags = append(p.StructTags, vtblPtrTag)
 	var (
 		structTags = make(map[string]bool)
 		unionTags  = make(map[string]bool)
@@ -92,11 +94,31 @@ func (p *Parser) initTaggedTypes(syms []*sym.Symbol) {
 				}
 				p.Enums[tag] = t
 				p.EnumTags = append(p.EnumTags, tag)
+			case sym.ClassTPDEF:
+				p.initTypedef(body.Name, body.Type, "")
+			}
+		case *sym.Def2:
+			switch body.Class {
+			case sym.ClassTPDEF:
+				p.initTypedef(body.Name, body.Type, body.Tag)
 			}
 		}
 	}
 }
 
+// initTypedef adds a scaffolding reference to the type definition with the
+// given name, so it may be referred to before defined.
+func (p *Parser) initTypedef(name string, t sym.Type, tag string) {
+	name = validName(name)
+	if _, ok := p.typedefRefs[name]; ok {
+		// Refer to the first of type definitions with duplicate names.
+		return
+	}
+	p.typedefRefs[name] = &c.TypedefType{Name: name}
+	p.typedefSymTypes[name] = t
+	p.typedefTags[name] = validName(tag)
+}
+
 // parseStructTag parses a struct tag sequence of symbols.
 func (p *Parser) parseStructTag(body *sym.Def, syms []*sym.Symbol) (n int) {
 	if base := body.Type.Base(); base != sym.BaseStruct {
@@ -120,16 +142,8 @@ func (p *Parser) parseStructTag(body *sym.Def, syms []*sym.Symbol) (n int) {
 				}
 				t.Fields = append(t.Fields, field)
 			case sym.ClassFIELD:
-				// TODO: Figure out what FIELD represents. Use method for now.
-				method := c.Field{
-					Offset: s.Hdr.Value,
-					Size:   body.Size,
-					Var: c.Var{
-						Type: p.parseType(body.Type, nil, ""),
-						Name: validName(body.Name),
-					},
-				}
-				t.Methods = append(t.Methods, method)
+				// C++ member function or static data member.
+				p.parseClassMember(t, body)
 			default:
 				panic(fmt.Errorf("support for class %q not yet implemented", body.Class))
 			}
@@ -218,7 +232,7 @@ func (p *Parser) parseEnumTag(body *sym.Def, syms []*sym.Symbol) (n int) {
 				name := validName(body.Name)
 				name = uniqueEnum(name, p.enumMembers)
 				member := &c.EnumMember{
-					Value: s.Hdr.Value,
+					Value: int32(s.Hdr.Value),
 					Name:  name,
 				}
 				t.Members = append(t.Members, member)
@@ -240,15 +254,26 @@ func (p *Parser) parseEnumTag(body *sym.Def, syms []*sym.Symbol) (n int) {
 // parseTypedef parses a typedef symbol.
 func (p *Parser) parseTypedef(t sym.Type, dims []uint32, tag, name string) {
 	name = validName(name)
+	var typ c.Type
+	if validName(tag) == name {
+		// Prevent type definition from referring to itself; the tag is that of
+		// the underlying type (e.g. typedef struct RECT RECT).
+		typ = parseMods(p.parseBase(t.Base(), tag), t.Mods(), dims)
+	} else {
+		typ = p.parseType(t, dims, tag)
+	}
 	def := &c.VarDecl{
 		Class: c.Typedef,
 		Var: c.Var{
-			Type: p.parseType(t, dims, tag),
+			Type: typ,
 			Name: name,
 		},
 	}
 	p.Typedefs = append(p.Typedefs, def)
 	p.Types[name] = def
+	if ref, ok := p.typedefRefs[name]; ok && ref.Type == nil {
+		ref.Type = def.Type
+	}
 }
 
 // ### [ Helper functions ] ####################################################
@@ -326,12 +351,138 @@ func findEnum(p *Parser, tag string) *c.EnumType {
 	}
 }
 
+// inferFieldEnumSizes infers the storage size of enums from the sizes of
+// fields using enums by value. The largest size in use is recorded.
+func (p *Parser) inferFieldEnumSizes() {
+	for _, tag := range p.StructTags {
+		for _, field := range p.Structs[tag].Fields {
+			inferEnumSize(field.Type, field.Size)
+		}
+	}
+	for _, tag := range p.UnionTags {
+		for _, field := range p.Unions[tag].Fields {
+			inferEnumSize(field.Type, field.Size)
+		}
+	}
+}
+
+// inferDeclEnumSizes infers the storage size of enums from the variables,
+// parameters and locals using enums by value. The largest size in use is
+// recorded.
+func (p *Parser) inferDeclEnumSizes() {
+	for _, overlay := range append([]*Overlay{p.Overlay}, p.Overlays...) {
+		for _, v := range overlay.Vars {
+			inferEnumSize(v.Type, v.Size)
+		}
+		for _, f := range overlay.Funcs {
+			if t, ok := f.Type.(*c.FuncType); ok {
+				for _, param := range t.Params {
+					inferEnumSize(param.Type, param.Size)
+				}
+			}
+			for _, block := range f.AllBlocks() {
+				for _, local := range block.Locals {
+					inferEnumSize(local.Type, local.Size)
+				}
+			}
+		}
+	}
+}
+
+// inferEnumSize records the size of a use by value of the given type, if an
+// enum. Uses disagreeing on the size are recorded as a conflict, and the largest
+// size is kept.
+func inferEnumSize(t c.Type, size uint32) {
+	e, ok := underlying(t).(*c.EnumType)
+	if !ok || size == 0 {
+		return
+	}
+	if e.Size != 0 && size != e.Size {
+		if len(e.UseSizes) == 0 {
+			e.UseSizes = append(e.UseSizes, e.Size)
+		}
+		known := false
+		for _, s := range e.UseSizes {
+			if s == size {
+				known = true
+				break
+			}
+		}
+		if !known {
+			e.UseSizes = append(e.UseSizes, size)
+		}
+	}
+	if size > e.Size {
+		e.Size = size
+	}
+}
+
 // parseType parses the SYM type into the equivalent C type.
 func (p *Parser) parseType(t sym.Type, dims []uint32, tag string) c.Type {
+	if u, ok := p.parseTypedefRef(t, dims, tag); ok {
+		return u
+	}
 	u := p.parseBase(t.Base(), tag)
 	return parseMods(u, t.Mods(), dims)
 }
 
+// parseTypedefRef parses the SYM type into the equivalent C type, referring to
+// the type definition named by the tag of the symbol. The boolean return value
+// indicates success.
+func (p *Parser) parseTypedefRef(t sym.Type, dims []uint32, tag string) (c.Type, bool) {
+	if p.ExpandTypedefs {
+		return nil, false
+	}
+	name := validName(tag)
+	ref, ok := p.typedefRefs[name]
+	if !ok {
+		return nil, false
+	}
+	// Struct, union and enum tags take precedence over type names, unless the
+	// type definition names the tagged type (e.g. typedef struct RECT RECT).
+	switch base := t.Base(); {
+	case p.typedefTags[name] == name && p.typedefSymTypes[name].Base() == base:
+		// Refer to type definition.
+	case base == sym.BaseStruct:
+		if _, ok := p.Structs[name]; ok {
+			return nil, false
+		}
+	case base == sym.BaseUnion:
+		if _, ok := p.Unions[name]; ok {
+			return nil, false
+		}
+	case base == sym.BaseEnum:
+		if _, ok := p.Enums[name]; ok {
+			return nil, false
+		}
+	}
+	// The type must be derived from the type definition; i.e. the modifiers of
+	// the type definition are the innermost modifiers of the type.
+	defType := p.typedefSymTypes[name]
+	if defType.Base() != t.Base() {
+		return nil, false
+	}
+	mods, defMods := t.Mods(), defType.Mods()
+	n := len(mods) - len(defMods)
+	if n < 0 {
+		return nil, false
+	}
+	ndims := 0
+	for i, mod := range defMods {
+		if mods[n+i] != mod {
+			return nil, false
+		}
+		if mod == sym.ModArray {
+			ndims++
+		}
+	}
+	// Dimensions are used from the innermost array and outwards.
+	if ndims > len(dims) {
+		return nil, false
+	}
+	return parseMods(ref, mods[:n], dims[ndims:]), true
+}
+
 // parseBase parses the SYM base type into the equivalent C type.
 func (p *Parser) parseBase(base sym.Base, tag string) c.Type {
 	tag = validName(tag)
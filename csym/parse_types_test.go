@@ -0,0 +1,84 @@
+package csym_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym"
+)
+
+func TestParseTypedefRef(t *testing.T) {
+	syms := []*sym.Symbol{
+		// struct RECT { RECT *next; int x; };
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 8, "RECT"),
+		def2(0, sym.ClassMOS, tPtr|sym.Type(sym.BaseStruct), 4, nil, "RECT", "next"),
+		def(4, sym.ClassMOS, sym.Type(sym.BaseInt), 4, "x"),
+		eos(),
+		// struct POINT { int x; };
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 4, "POINT"),
+		def(0, sym.ClassMOS, sym.Type(sym.BaseInt), 4, "x"),
+		eos(),
+		// typedef struct RECT RECT;
+		def2(0, sym.ClassTPDEF, sym.Type(sym.BaseStruct), 8, nil, "RECT", "RECT"),
+		// typedef struct RECT POINT;
+		def2(0, sym.ClassTPDEF, sym.Type(sym.BaseStruct), 8, nil, "RECT", "POINT"),
+		def2(0x80010000, sym.ClassEXT, tPtr|sym.Type(sym.BaseStruct), 4, nil, "RECT", "r"),
+		def2(0x80010004, sym.ClassEXT, sym.Type(sym.BaseStruct), 4, nil, "POINT", "pt"),
+	}
+	p := parse(t, syms)
+	golden := []struct {
+		got, want string
+	}{
+		// Type definition naming the tagged type.
+		{got: p.Structs["RECT"].Fields[0].String(), want: "RECT *next"},
+		{got: p.Vars[0].Var.String(), want: "RECT *r"},
+		// Tag of other type than the type definition.
+		{got: p.Vars[1].Var.String(), want: "struct POINT pt"},
+		// Type definitions.
+		{got: p.Types["RECT"].Def(), want: "typedef struct RECT RECT"},
+		{got: p.Types["POINT"].Def(), want: "typedef RECT POINT"},
+	}
+	for i, g := range golden {
+		if g.got != g.want {
+			t.Errorf("i=%d: type mismatch; expected %q, got %q", i, g.want, g.got)
+		}
+	}
+}
+
+func TestInferEnumSize(t *testing.T) {
+	syms := []*sym.Symbol{
+		// enum STATE { IDLE, RUN };
+		def(0, sym.ClassENTAG, sym.Type(sym.BaseEnum), 4, "STATE"),
+		def(0, sym.ClassMOE, sym.Type(sym.BaseInt), 4, "IDLE"),
+		def(1, sym.ClassMOE, sym.Type(sym.BaseInt), 4, "RUN"),
+		eos(),
+		// enum DIR { LEFT, RIGHT };
+		def(0, sym.ClassENTAG, sym.Type(sym.BaseEnum), 4, "DIR"),
+		def(0, sym.ClassMOE, sym.Type(sym.BaseInt), 4, "LEFT"),
+		def(1, sym.ClassMOE, sym.Type(sym.BaseInt), 4, "RIGHT"),
+		eos(),
+		// struct ACTOR { enum STATE state; enum DIR dir; };
+		def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 2, "ACTOR"),
+		def2(0, sym.ClassMOS, sym.Type(sym.BaseEnum), 1, nil, "STATE", "state"),
+		def2(1, sym.ClassMOS, sym.Type(sym.BaseEnum), 1, nil, "DIR", "dir"),
+		eos(),
+		// enum STATE state; enum DIR dir;
+		def2(0x80010000, sym.ClassEXT, sym.Type(sym.BaseEnum), 4, nil, "STATE", "state"),
+		def2(0x80010004, sym.ClassEXT, sym.Type(sym.BaseEnum), 1, nil, "DIR", "dir"),
+	}
+	p := parse(t, syms)
+	golden := []struct {
+		tag  string
+		want string
+	}{
+		// Uses disagree on the size; the largest is kept, and the conflict is
+		// recorded.
+		{tag: "STATE", want: "// size: 0x4\n// conflicting use sizes: 0x1, 0x4\nenum STATE {\n\tIDLE = 0,\n\tRUN  = 1,\n}"},
+		// Uses agree on the size.
+		{tag: "DIR", want: "// size: 0x1\nenum DIR {\n\tLEFT  = 0,\n\tRIGHT = 1,\n}"},
+	}
+	for i, g := range golden {
+		if got := p.Enums[g.tag].Def(); got != g.want {
+			t.Errorf("i=%d: enum definition mismatch; expected %q, got %q", i, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package csym
+
+import (
+	"sort"
+
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// MIPS argument registers.
+const (
+	regA0 c.Reg = 4
+	regA3 c.Reg = 7
+)
+
+// variadicFuncs specifies the names of known variadic library functions.
+var variadicFuncs = map[string]bool{
+	"printf":   true,
+	"sprintf":  true,
+	"fprintf":  true,
+	"scanf":    true,
+	"sscanf":   true,
+	"fscanf":   true,
+	"FntPrint": true,
+}
+
+// recoverPrototypes recovers the function prototypes of function declarations;
+// i.e. parameter order and variadic functions, based on the parameter
+// locations and stack frames of functions with debug information.
+func (p *Parser) recoverPrototypes() {
+	for _, overlay := range append([]*Overlay{p.Overlay}, p.Overlays...) {
+		for _, f := range overlay.Funcs {
+			funcType, ok := f.Type.(*c.FuncType)
+			if !ok {
+				continue
+			}
+			if f.Frame != nil {
+				orderParams(f.Frame, funcType)
+				if hasStackArgs(f.Frame, funcType) {
+					funcType.Variadic = true
+				}
+			}
+			// The parameter list is only known for functions with parameters
+			// or scope blocks (i.e. a function body with debug information);
+			// the absence of parameters then implies (void).
+			if len(funcType.Params) > 0 || len(f.Blocks) > 0 {
+				funcType.Prototyped = true
+			}
+			// Note, library functions lack debug information, and thus
+			// parameters.
+			if variadicFuncs[f.Name] {
+				funcType.Variadic = true
+			}
+		}
+	}
+}
+
+// orderParams orders the parameters of the given function type by argument
+// slot; i.e. by argument register, and then by stack slot in the argument area
+// of the caller. Parameters of unknown slot (e.g. kept in saved registers) fill
+// the remaining slots in order of appearance.
+func orderParams(frame *c.Frame, t *c.FuncType) {
+	slots := make(map[int]*c.VarDecl)
+	var unknown []*c.VarDecl
+	for _, param := range t.Params {
+		slot, ok := argSlot(frame, param)
+		if !ok || slots[slot] != nil {
+			unknown = append(unknown, param)
+			continue
+		}
+		slots[slot] = param
+	}
+	var keys []int
+	for slot := range slots {
+		keys = append(keys, slot)
+	}
+	sort.Ints(keys)
+	params := make([]*c.VarDecl, 0, len(t.Params))
+	for _, slot := range keys {
+		// Fill gaps with parameters of unknown slot.
+		for len(params) < slot && len(unknown) > 0 {
+			params = append(params, unknown[0])
+			unknown = unknown[1:]
+		}
+		params = append(params, slots[slot])
+	}
+	params = append(params, unknown...)
+	t.Params = params
+}
+
+// hasStackArgs reports whether the stack frame has variables located in the
+// argument area of the caller, beyond the declared parameters of the given
+// function type.
+func hasStackArgs(frame *c.Frame, t *c.FuncType) bool {
+	params := make(map[*c.VarDecl]bool)
+	end := int32(frame.Size) + 4*int32(len(t.Params))
+	for _, param := range t.Params {
+		params[param] = true
+		// Parameters passed by value may occupy several stack slots.
+		if loc, ok := param.Loc.(*c.StackLoc); ok {
+			if paramEnd := loc.Offset + int32((param.Size+3)&^3); paramEnd > end {
+				end = paramEnd
+			}
+		}
+	}
+	for _, v := range frame.Vars {
+		if params[v] {
+			continue
+		}
+		if loc, ok := v.Loc.(*c.StackLoc); ok && loc.Offset >= end {
+			return true
+		}
+	}
+	return false
+}
+
+// argSlot returns the argument slot of the given parameter, as determined by
+// its location.
+func argSlot(frame *c.Frame, param *c.VarDecl) (int, bool) {
+	switch loc := param.Loc.(type) {
+	case *c.RegLoc:
+		if regA0 <= loc.Reg && loc.Reg <= regA3 {
+			return int(loc.Reg - regA0), true
+		}
+	case *c.StackLoc:
+		// Stack arguments are located in the argument area of the caller,
+		// directly above the stack frame.
+		offset := loc.Offset - int32(frame.Size)
+		if offset >= 0 && offset%4 == 0 {
+			return int(offset / 4), true
+		}
+	}
+	return 0, false
+}
+
+// isPromoted reports whether the given parameter type is the default argument
+// promotion of the given declared type, as used by K&R function definitions.
+func isPromoted(paramType, declType c.Type) bool {
+	// Note, the declared type may be a type definition; e.g. u_char.
+	declType = underlying(declType)
+	switch underlying(paramType) {
+	case c.Int:
+		return declType == c.Char || declType == c.Short || declType == c.UChar || declType == c.UShort
+	case c.UInt:
+		return declType == c.UShort
+	}
+	return false
+}
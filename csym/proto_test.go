@@ -0,0 +1,61 @@
+package csym_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym"
+)
+
+func TestRecoverPrototypes(t *testing.T) {
+	const path = `C:\GAME\MAIN.C`
+	syms := []*sym.Symbol{
+		def(0, sym.ClassTPDEF, sym.Type(sym.BaseUChar), 0, "u_char"),
+		def(0x80010000, sym.ClassEXT, tFcn|sym.Type(sym.BaseInt), 0, "order"),
+		def(0x80010100, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "noargs"),
+		def(0x80010200, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "kr"),
+		def(0x80010300, sym.ClassEXT, tFcn|sym.Type(sym.BaseInt), 0, "printf"),
+		def(0x80010400, sym.ClassEXT, tFcn|sym.Type(sym.BaseVoid), 0, "unknown"),
+		// int order(int a, int b, int c); parameters out of argument order.
+		newSym(0x80010000, &sym.FuncStart{FP: 29, FSize: 24, RetReg: 31, Line: 10, Path: path, Name: "order"}),
+		def(17, sym.ClassREGPARM, sym.Type(sym.BaseInt), 4, "c"),
+		def(5, sym.ClassREGPARM, sym.Type(sym.BaseInt), 4, "b"),
+		def(4, sym.ClassREGPARM, sym.Type(sym.BaseInt), 4, "a"),
+		newSym(0x80010008, &sym.BlockStart{Line: 1}),
+		newSym(0x80010020, &sym.BlockEnd{Line: 2}),
+		newSym(0x80010040, &sym.FuncEnd{Line: 3}),
+		// void noargs(void);
+		newSym(0x80010100, &sym.FuncStart{FP: 29, FSize: 8, RetReg: 31, Line: 20, Path: path, Name: "noargs"}),
+		newSym(0x80010108, &sym.BlockStart{Line: 1}),
+		newSym(0x80010110, &sym.BlockEnd{Line: 2}),
+		newSym(0x80010118, &sym.FuncEnd{Line: 2}),
+		// void kr(c) u_char c; passed as int, declared through a typedef.
+		newSym(0x80010200, &sym.FuncStart{FP: 29, FSize: 8, RetReg: 31, Line: 30, Path: path, Name: "kr"}),
+		def(4, sym.ClassREGPARM, sym.Type(sym.BaseInt), 4, "c"),
+		def2(16, sym.ClassREG, sym.Type(sym.BaseUChar), 1, nil, "u_char", "c"),
+		newSym(0x80010208, &sym.BlockStart{Line: 2}),
+		newSym(0x80010210, &sym.BlockEnd{Line: 3}),
+		newSym(0x80010218, &sym.FuncEnd{Line: 3}),
+	}
+	p := parse(t, syms)
+	golden := map[string]string{
+		"order":   "int order(int a, int b, int c)",
+		"noargs":  "void noargs(void)",
+		"kr":      "void kr(u_char c)",
+		"printf":  "int printf(...)",
+		"unknown": "void unknown()",
+	}
+	for _, f := range p.Funcs {
+		want, ok := golden[f.Name]
+		if !ok {
+			t.Errorf("unexpected function %q", f.Name)
+			continue
+		}
+		if got := f.Var.String(); got != want {
+			t.Errorf("prototype of %q mismatch; expected %q, got %q", f.Name, want, got)
+		}
+		delete(golden, f.Name)
+	}
+	for name := range golden {
+		t.Errorf("function %q not found", name)
+	}
+}
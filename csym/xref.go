@@ -0,0 +1,249 @@
+package csym
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sanctuary/sym/csym/c"
+)
+
+//go:generate stringer -linecomment -type XrefKind
+
+// XrefKind specifies the kind of a type cross-reference.
+type XrefKind uint8
+
+// Cross-reference kinds.
+const (
+	// Field of structure or union type.
+	XrefField XrefKind = iota + 1 // field
+	// Type definition.
+	XrefTypedef // typedef
+	// Global variable.
+	XrefGlobal // global
+	// Function return type.
+	XrefReturn // return
+	// Function parameter.
+	XrefParam // param
+	// Local variable.
+	XrefLocal // local
+)
+
+// KeyKind specifies the kind of named type of a cross-reference key.
+type KeyKind uint8
+
+// Key kinds.
+const (
+	// Structure type; e.g. "struct RECT".
+	KeyStruct KeyKind = iota + 1
+	// Union type; e.g. "union U".
+	KeyUnion
+	// Enum type; e.g. "enum E".
+	KeyEnum
+	// Type definition; e.g. "u_long".
+	KeyTypedef
+)
+
+// An Xref is a cross-reference to a type; i.e. a use of the type.
+type Xref struct {
+	// Cross-reference kind.
+	Kind XrefKind
+	// Name of the user of the type; e.g. "PlayerStruct.pos" for fields,
+	// "plr" for globals, "DrawPlayer" for return types, and "DrawPlayer.p" for
+	// parameters and locals.
+	Name string
+	// Declared type of the user.
+	Type c.Type
+	// The type is embedded by value; i.e. not referred to through a pointer or
+	// function type.
+	Embedded bool
+	// Enclosing structure or union type of fields (optional).
+	Parent c.Type
+	// Enclosing function of returns, parameters and locals (optional).
+	Func *c.FuncDecl
+	// Global variable declaration of globals (optional).
+	Var *c.VarDecl
+}
+
+// An XrefIndex maps from named types to their users.
+type XrefIndex struct {
+	// xrefs maps from type key to cross-references; e.g. "struct RECT", "union
+	// U", "enum E" and "u_long" for type definitions.
+	xrefs map[string][]*Xref
+	// kinds maps from type key to key kind.
+	kinds map[string]KeyKind
+}
+
+// NewXrefIndex returns a new type cross-reference index of the types and
+// declarations recorded by the parser.
+func NewXrefIndex(p *Parser) *XrefIndex {
+	x := &XrefIndex{
+		xrefs: make(map[string][]*Xref),
+		kinds: make(map[string]KeyKind),
+	}
+	// Fields.
+	for _, tag := range p.StructTags {
+		t := p.Structs[tag]
+		for _, field := range t.Fields {
+			x.add(field.Type, &Xref{Kind: XrefField, Name: tag + "." + field.Name, Type: field.Type, Parent: t})
+		}
+	}
+	for _, tag := range p.UnionTags {
+		t := p.Unions[tag]
+		for _, field := range t.Fields {
+			x.add(field.Type, &Xref{Kind: XrefField, Name: tag + "." + field.Name, Type: field.Type, Parent: t})
+		}
+	}
+	// Type definitions.
+	for _, def := range p.Typedefs {
+		if def, ok := def.(*c.VarDecl); ok {
+			x.add(def.Type, &Xref{Kind: XrefTypedef, Name: def.Name, Type: def.Type})
+		}
+	}
+	// Declarations.
+	for _, overlay := range append([]*Overlay{p.Overlay}, p.Overlays...) {
+		for _, v := range overlay.Vars {
+			x.add(v.Type, &Xref{Kind: XrefGlobal, Name: v.Name, Type: v.Type, Var: v})
+		}
+		for _, f := range overlay.Funcs {
+			funcType, ok := f.Type.(*c.FuncType)
+			if !ok {
+				continue
+			}
+			x.add(funcType.RetType, &Xref{Kind: XrefReturn, Name: f.Name, Type: funcType.RetType, Func: f})
+			for _, param := range funcType.Params {
+				x.add(param.Type, &Xref{Kind: XrefParam, Name: f.Name + "." + param.Name, Type: param.Type, Func: f})
+			}
+			for _, block := range f.AllBlocks() {
+				for _, local := range block.Locals {
+					x.add(local.Type, &Xref{Kind: XrefLocal, Name: f.Name + "." + local.Name, Type: local.Type, Func: f})
+				}
+			}
+		}
+	}
+	return x
+}
+
+// Lookup returns the users of the named type. The name is either a type key
+// (e.g. "struct RECT") or a bare tag or type definition name (e.g. "RECT"), in
+// which case the users of all matching types are returned.
+func (x *XrefIndex) Lookup(name string) []*Xref {
+	if strings.Contains(name, " ") {
+		return x.xrefs[name]
+	}
+	var xrefs []*Xref
+	for _, key := range []string{name, "struct " + name, "union " + name, "enum " + name} {
+		xrefs = append(xrefs, x.xrefs[key]...)
+	}
+	return xrefs
+}
+
+// Embedders returns the structure and union types which embed the named type by
+// value.
+func (x *XrefIndex) Embedders(name string) []c.Type {
+	var ts []c.Type
+	seen := make(map[c.Type]bool)
+	for _, xref := range x.Lookup(name) {
+		if xref.Kind != XrefField || !xref.Embedded || seen[xref.Parent] {
+			continue
+		}
+		seen[xref.Parent] = true
+		ts = append(ts, xref.Parent)
+	}
+	return ts
+}
+
+// FuncKeys returns the type keys of the named types referred to by the return
+// type, parameters and locals of the given function, in sorted order.
+func (x *XrefIndex) FuncKeys(f *c.FuncDecl) []string {
+	var keys []string
+	for _, key := range x.Keys() {
+		for _, xref := range x.xrefs[key] {
+			if xref.Func == f {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	return keys
+}
+
+// Kind returns the kind of named type of the given type key, or 0 if not
+// present in the index.
+func (x *XrefIndex) Kind(key string) KeyKind {
+	return x.kinds[key]
+}
+
+// Keys returns the type keys of the index in sorted order.
+func (x *XrefIndex) Keys() []string {
+	var keys []string
+	for key := range x.xrefs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// add adds a cross-reference from the given user to each named type referred to
+// by the given type expression.
+func (x *XrefIndex) add(t c.Type, user *Xref) {
+	refs := make(map[string]*namedRef)
+	namedTypes(t, true, refs)
+	// Add in sorted order for deterministic output.
+	var keys []string
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		xref := *user
+		xref.Embedded = refs[key].embedded
+		x.xrefs[key] = append(x.xrefs[key], &xref)
+		x.kinds[key] = refs[key].kind
+	}
+}
+
+// A namedRef is a reference to a named type.
+type namedRef struct {
+	// Kind of named type.
+	kind KeyKind
+	// The type is embedded by value by any of its references.
+	embedded bool
+}
+
+// namedTypes records the named types referred to by the given type expression,
+// mapping from type key to reference.
+func namedTypes(t c.Type, embedded bool, refs map[string]*namedRef) {
+	switch t := t.(type) {
+	case *c.StructType:
+		addRef(refs, "struct "+t.Tag, KeyStruct, embedded)
+	case *c.UnionType:
+		addRef(refs, "union "+t.Tag, KeyUnion, embedded)
+	case *c.EnumType:
+		addRef(refs, "enum "+t.Tag, KeyEnum, embedded)
+	case *c.TypedefType:
+		addRef(refs, t.Name, KeyTypedef, embedded)
+		namedTypes(t.Type, embedded, refs)
+	case *c.VarDecl:
+		addRef(refs, t.Name, KeyTypedef, embedded)
+		namedTypes(t.Type, embedded, refs)
+	case *c.PointerType:
+		namedTypes(t.Elem, false, refs)
+	case *c.ArrayType:
+		namedTypes(t.Elem, embedded, refs)
+	case *c.FuncType:
+		namedTypes(t.RetType, false, refs)
+		for _, param := range t.Params {
+			namedTypes(param.Type, false, refs)
+		}
+	}
+}
+
+// addRef records a reference to the named type of the given key.
+func addRef(refs map[string]*namedRef, key string, kind KeyKind, embedded bool) {
+	ref, ok := refs[key]
+	if !ok {
+		ref = &namedRef{kind: kind}
+		refs[key] = ref
+	}
+	ref.embedded = ref.embedded || embedded
+}
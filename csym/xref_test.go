@@ -0,0 +1,100 @@
+package csym_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sanctuary/sym"
+	"github.com/sanctuary/sym/csym"
+)
+
+// xrefSyms are symbols of types used by fields, type definitions, globals,
+// returns, parameters and locals.
+var xrefSyms = []*sym.Symbol{
+	// struct RECT { int x; };
+	def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 4, "RECT"),
+	def(0, sym.ClassMOS, sym.Type(sym.BaseInt), 4, "x"),
+	eos(),
+	// struct BOX { struct RECT r; struct RECT *p; };
+	def(0, sym.ClassSTRTAG, sym.Type(sym.BaseStruct), 8, "BOX"),
+	def2(0, sym.ClassMOS, sym.Type(sym.BaseStruct), 4, nil, "RECT", "r"),
+	def2(4, sym.ClassMOS, tPtr|sym.Type(sym.BaseStruct), 4, nil, "RECT", "p"),
+	eos(),
+	// typedef struct RECT RECT_T;
+	def2(0, sym.ClassTPDEF, sym.Type(sym.BaseStruct), 4, nil, "RECT", "RECT_T"),
+	// struct BOX box;
+	def2(0x80090000, sym.ClassEXT, sym.Type(sym.BaseStruct), 8, nil, "BOX", "box"),
+	// struct RECT *Grow(struct RECT *r) { struct BOX b; }
+	def2(0x80010000, sym.ClassEXT, tFcn|tPtr<<2|sym.Type(sym.BaseStruct), 0, nil, "RECT", "Grow"),
+	newSym(0x80010000, &sym.FuncStart{FP: 29, FSize: 24, RetReg: 31, Line: 10, Path: "MAIN.C", Name: "Grow"}),
+	def2(4, sym.ClassREGPARM, tPtr|sym.Type(sym.BaseStruct), 4, nil, "RECT", "r"),
+	newSym(0x80010008, &sym.BlockStart{Line: 1}),
+	def2(16, sym.ClassAUTO, sym.Type(sym.BaseStruct), 8, nil, "BOX", "b"),
+	newSym(0x80010020, &sym.BlockEnd{Line: 2}),
+	newSym(0x80010040, &sym.FuncEnd{Line: 3}),
+}
+
+func TestXrefIndex(t *testing.T) {
+	p := parse(t, xrefSyms)
+	x := csym.NewXrefIndex(p)
+	golden := []struct {
+		name string
+		want []string
+	}{
+		{
+			name: "RECT",
+			want: []string{
+				"field BOX.r (embedded)",
+				"field BOX.p",
+				"typedef RECT_T (embedded)",
+				"return Grow",
+				"param Grow.r",
+			},
+		},
+		{
+			name: "struct BOX",
+			want: []string{
+				"global box (embedded)",
+				"local Grow.b (embedded)",
+			},
+		},
+		{
+			name: "struct POINT",
+			want: nil,
+		},
+	}
+	for i, g := range golden {
+		var got []string
+		for _, xref := range x.Lookup(g.name) {
+			s := fmt.Sprintf("%s %s", xref.Kind, xref.Name)
+			if xref.Embedded {
+				s += " (embedded)"
+			}
+			got = append(got, s)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(g.want) {
+			t.Errorf("i=%d: cross-references of %q mismatch; expected %q, got %q", i, g.name, g.want, got)
+		}
+	}
+	// Embedders.
+	embedders := x.Embedders("RECT")
+	if len(embedders) != 1 || embedders[0] != p.Structs["BOX"] {
+		t.Errorf("embedders of %q mismatch; expected [struct BOX], got %v", "RECT", embedders)
+	}
+	// Types used by function.
+	want := []string{"struct BOX", "struct RECT"}
+	if got := x.FuncKeys(p.Funcs[0]); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("types of function %q mismatch; expected %q, got %q", p.Funcs[0].Name, want, got)
+	}
+	// Key kinds.
+	kinds := map[string]csym.KeyKind{
+		"struct RECT":  csym.KeyStruct,
+		"struct BOX":   csym.KeyStruct,
+		"struct POINT": 0,
+	}
+	for key, want := range kinds {
+		if got := x.Kind(key); got != want {
+			t.Errorf("kind of %q mismatch; expected %d, got %d", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,825 @@
+// Package demangle implements demangling of C++ symbol names in the old GNU
+// mangling scheme (pre-Itanium), as used by GCC 2.7 and 2.8 and the CCPSX
+// compiler of the Psy-Q SDK.
+package demangle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//go:generate stringer -linecomment -type Kind
+
+// Kind specifies the kind of a demangled symbol.
+type Kind uint8
+
+// Symbol kinds.
+const (
+	// Non-member function.
+	KindFunc Kind = iota + 1 // function
+	// Member function.
+	KindMethod // method
+	// Constructor.
+	KindCtor // constructor
+	// Destructor.
+	KindDtor // destructor
+	// Static data member.
+	KindStatic // static member
+	// Virtual table.
+	KindVtable // virtual table
+	// Global constructors or destructors of a translation unit.
+	KindGlobal // global
+)
+
+// A Symbol is a demangled C++ symbol.
+type Symbol struct {
+	// Symbol kind.
+	Kind Kind
+	// Qualified class name (e.g. "Foo::Bar"); empty for non-members.
+	Class string
+	// Unqualified name (e.g. "Draw", "operator+", "~Sprite").
+	Name string
+	// Parameter types of functions.
+	Params []string
+	// Variadic function.
+	Variadic bool
+	// Const member function.
+	Const bool
+}
+
+// String returns the string representation of the symbol; e.g.
+// "CList::Add(int, char *)".
+func (sym *Symbol) String() string {
+	buf := &strings.Builder{}
+	switch sym.Kind {
+	case KindVtable:
+		fmt.Fprintf(buf, "%s virtual table", sym.Class)
+		return buf.String()
+	case KindGlobal:
+		return sym.Name
+	}
+	if len(sym.Class) > 0 {
+		fmt.Fprintf(buf, "%s::", sym.Class)
+	}
+	buf.WriteString(sym.Name)
+	if sym.Kind == KindStatic {
+		return buf.String()
+	}
+	buf.WriteString("(")
+	params := sym.Params
+	if len(params) == 0 && !sym.Variadic {
+		params = []string{"void"}
+	}
+	buf.WriteString(strings.Join(params, ", "))
+	if sym.Variadic {
+		if len(params) > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("...")
+	}
+	buf.WriteString(")")
+	if sym.Const {
+		buf.WriteString(" const")
+	}
+	return buf.String()
+}
+
+// Demangle returns the demangled representation of the given mangled name.
+func Demangle(name string) (string, error) {
+	sym, err := Parse(name)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return sym.String(), nil
+}
+
+// Parse parses the given mangled name.
+func Parse(name string) (*Symbol, error) {
+	// Virtual tables.
+	for _, prefix := range []string{"_vt$", "_vt.", "__vt_"} {
+		if strings.HasPrefix(name, prefix) {
+			d := newDecoder(name[len(prefix):])
+			class, err := d.vtableClass()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return &Symbol{Kind: KindVtable, Class: class}, nil
+		}
+	}
+	// Global constructors and destructors.
+	for _, prefix := range []string{"_GLOBAL_$I$", "_GLOBAL_.I."} {
+		if strings.HasPrefix(name, prefix) {
+			return &Symbol{Kind: KindGlobal, Name: "global constructors keyed to " + name[len(prefix):]}, nil
+		}
+	}
+	for _, prefix := range []string{"_GLOBAL_$D$", "_GLOBAL_.D."} {
+		if strings.HasPrefix(name, prefix) {
+			return &Symbol{Kind: KindGlobal, Name: "global destructors keyed to " + name[len(prefix):]}, nil
+		}
+	}
+	// Destructors.
+	for _, prefix := range []string{"_$_", "_._"} {
+		if strings.HasPrefix(name, prefix) {
+			d := newDecoder(name[len(prefix):])
+			class, last, err := d.className()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if !d.eof() {
+				return nil, errors.Errorf("invalid destructor %q; trailing characters %q", name, d.rest())
+			}
+			return &Symbol{Kind: KindDtor, Class: class, Name: "~" + last}, nil
+		}
+	}
+	// Static data members.
+	if strings.HasPrefix(name, "_") && len(name) > 1 && (isDigit(name[1]) || name[1] == 'Q' || name[1] == 't') {
+		d := newDecoder(name[1:])
+		if class, _, err := d.className(); err == nil && !d.eof() {
+			if sep := d.peek(); sep == '$' || sep == '.' {
+				d.next()
+				if !d.eof() {
+					return &Symbol{Kind: KindStatic, Class: class, Name: d.rest()}, nil
+				}
+			}
+		}
+	}
+	// Constructors and operators.
+	if strings.HasPrefix(name, "__") {
+		sig := name[len("__"):]
+		if len(sig) > 0 && (isDigit(sig[0]) || sig[0] == 'Q' || sig[0] == 't') {
+			sym, err := parseSignature("", sig)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if sym.Kind != KindMethod {
+				return nil, errors.Errorf("invalid constructor %q", name)
+			}
+			sym.Kind = KindCtor
+			sym.Name = lastName(sym.Class)
+			return sym, nil
+		}
+		if sym, ok := parseOperator(sig); ok {
+			return sym, nil
+		}
+	}
+	// Functions and methods; locate the first "__" followed by a valid
+	// signature.
+	for i := 1; i+len("__") < len(name); i++ {
+		if name[i] != '_' || name[i+1] != '_' {
+			continue
+		}
+		if sym, err := parseSignature(name[:i], name[i+len("__"):]); err == nil {
+			return sym, nil
+		}
+	}
+	return nil, errors.Errorf("unable to demangle %q", name)
+}
+
+// parseOperator parses the given operator name and signature (following the
+// "__" prefix); e.g. "ml__3Fooi" or "opi__3Foo".
+func parseOperator(s string) (*Symbol, bool) {
+	end := strings.Index(s, "__")
+	for end != -1 {
+		code, sig := s[:end], s[end+len("__"):]
+		if op, ok := operators[code]; ok {
+			if sym, err := parseSignature("operator"+op, sig); err == nil {
+				return sym, true
+			}
+		}
+		// Type conversion operator.
+		if strings.HasPrefix(code, "op") {
+			d := newDecoder(code[len("op"):])
+			if t, err := d.typ(); err == nil && d.eof() {
+				if sym, err := parseSignature("operator "+t.String(), sig); err == nil {
+					return sym, true
+				}
+			}
+		}
+		next := strings.Index(s[end+1:], "__")
+		if next == -1 {
+			break
+		}
+		end += 1 + next
+	}
+	return nil, false
+}
+
+// operators maps from operator code to operator.
+var operators = map[string]string{
+	"nw":  " new",
+	"dl":  " delete",
+	"vn":  " new []",
+	"vd":  " delete []",
+	"as":  "=",
+	"ne":  "!=",
+	"eq":  "==",
+	"ge":  ">=",
+	"gt":  ">",
+	"le":  "<=",
+	"lt":  "<",
+	"pl":  "+",
+	"apl": "+=",
+	"mi":  "-",
+	"ami": "-=",
+	"ml":  "*",
+	"aml": "*=",
+	"dv":  "/",
+	"adv": "/=",
+	"md":  "%",
+	"amd": "%=",
+	"aa":  "&&",
+	"oo":  "||",
+	"nt":  "!",
+	"pp":  "++",
+	"mm":  "--",
+	"ad":  "&",
+	"aad": "&=",
+	"or":  "|",
+	"aor": "|=",
+	"er":  "^",
+	"aer": "^=",
+	"co":  "~",
+	"ls":  "<<",
+	"als": "<<=",
+	"rs":  ">>",
+	"ars": ">>=",
+	"rf":  "->",
+	"rm":  "->*",
+	"cl":  "()",
+	"vc":  "[]",
+	"cm":  ",",
+}
+
+// parseSignature parses the signature of the function or method with the
+// given name.
+//
+//	F<params>             non-member function
+//	[C]<class><params>    (const) member function
+func parseSignature(name, sig string) (*Symbol, error) {
+	d := newDecoder(sig)
+	sym := &Symbol{Name: name}
+	switch c := d.peek(); {
+	case c == 'F':
+		d.next()
+		sym.Kind = KindFunc
+	default:
+		if c == 'C' {
+			d.next()
+			sym.Const = true
+		}
+		class, _, err := d.className()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sym.Kind = KindMethod
+		sym.Class = class
+	}
+	params, variadic, err := d.params()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !d.eof() {
+		return nil, errors.Errorf("invalid signature %q; trailing characters %q", sig, d.rest())
+	}
+	for _, param := range params {
+		sym.Params = append(sym.Params, param.String())
+	}
+	sym.Variadic = variadic
+	return sym, nil
+}
+
+// lastName returns the last component of the given qualified name.
+func lastName(name string) string {
+	if pos := strings.LastIndex(name, "::"); pos != -1 {
+		return name[pos+len("::"):]
+	}
+	// Strip template arguments.
+	if pos := strings.Index(name, "<"); pos != -1 {
+		return name[:pos]
+	}
+	return name
+}
+
+// ### [ Decoder ] #############################################################
+
+// decoder decodes mangled types.
+type decoder struct {
+	// Mangled input.
+	s string
+	// Current position in input.
+	pos int
+	// Remembered parameter types, referred to by T and N.
+	types []*typ
+}
+
+// newDecoder returns a new decoder for the given mangled input.
+func newDecoder(s string) *decoder {
+	return &decoder{s: s}
+}
+
+// eof reports whether the end of input has been reached.
+func (d *decoder) eof() bool {
+	return d.pos >= len(d.s)
+}
+
+// peek returns the current character, or 0 at the end of input.
+func (d *decoder) peek() byte {
+	if d.eof() {
+		return 0
+	}
+	return d.s[d.pos]
+}
+
+// next returns the current character and advances the position.
+func (d *decoder) next() byte {
+	c := d.peek()
+	d.pos++
+	return c
+}
+
+// rest returns the remaining input.
+func (d *decoder) rest() string {
+	if d.eof() {
+		return ""
+	}
+	return d.s[d.pos:]
+}
+
+// number decodes a decimal number.
+func (d *decoder) number() (int, error) {
+	start := d.pos
+	for !d.eof() && isDigit(d.peek()) {
+		d.pos++
+	}
+	if start == d.pos {
+		return 0, errors.Errorf("expected number at %q", d.s[start:])
+	}
+	n, err := strconv.Atoi(d.s[start:d.pos])
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return n, nil
+}
+
+// count decodes a count; a single digit, or a number delimited by underscores
+// if greater than 9 (e.g. "_12_").
+func (d *decoder) count() (int, error) {
+	if d.peek() == '_' {
+		d.next()
+		n, err := d.number()
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if d.next() != '_' {
+			return 0, errors.Errorf("expected '_' after count %d", n)
+		}
+		return n, nil
+	}
+	if !isDigit(d.peek()) {
+		return 0, errors.Errorf("expected count at %q", d.rest())
+	}
+	return int(d.next() - '0'), nil
+}
+
+// name decodes a length-prefixed name (e.g. "5CList").
+func (d *decoder) name() (string, error) {
+	n, err := d.number()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if n == 0 || d.pos+n > len(d.s) {
+		return "", errors.Errorf("invalid name length %d", n)
+	}
+	name := d.s[d.pos : d.pos+n]
+	d.pos += n
+	return name, nil
+}
+
+// className decodes a (possibly qualified or template) class name, and returns
+// the qualified name and its last component.
+//
+//	5CList            CList
+//	Q23Foo3Bar        Foo::Bar
+//	t4List1Zi         List<int>
+func (d *decoder) className() (qualified, last string, err error) {
+	switch c := d.peek(); {
+	case isDigit(c):
+		name, err := d.name()
+		if err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		return name, name, nil
+	case c == 't':
+		d.next()
+		name, err := d.template()
+		if err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		return name, lastName(name), nil
+	case c == 'Q':
+		d.next()
+		n, err := d.count()
+		if err != nil {
+			return "", "", errors.WithStack(err)
+		}
+		// Optional separator (e.g. "Q2_3Foo3Bar").
+		if d.peek() == '_' {
+			d.next()
+		}
+		var names []string
+		for i := 0; i < n; i++ {
+			name, _, err := d.className()
+			if err != nil {
+				return "", "", errors.WithStack(err)
+			}
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return "", "", errors.New("empty qualified name")
+		}
+		return strings.Join(names, "::"), lastName(names[len(names)-1]), nil
+	default:
+		return "", "", errors.Errorf("expected class name at %q", d.rest())
+	}
+}
+
+// template decodes a template instance name (following the 't' prefix).
+//
+//	<name><nargs>{Z<type> | <type><value>}
+func (d *decoder) template() (string, error) {
+	name, err := d.name()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	n, err := d.count()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	var args []string
+	for i := 0; i < n; i++ {
+		if d.peek() == 'Z' {
+			// Type argument.
+			d.next()
+			t, err := d.typ()
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			args = append(args, t.String())
+			continue
+		}
+		// Non-type argument.
+		if _, err := d.typ(); err != nil {
+			return "", errors.WithStack(err)
+		}
+		neg := false
+		if d.peek() == 'm' {
+			d.next()
+			neg = true
+		}
+		v, err := d.number()
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if neg {
+			v = -v
+		}
+		args = append(args, strconv.Itoa(v))
+	}
+	arg := strings.Join(args, ", ")
+	if strings.HasSuffix(arg, ">") {
+		arg += " "
+	}
+	return fmt.Sprintf("%s<%s>", name, arg), nil
+}
+
+// vtableClass decodes the class name of a virtual table; classes of nested
+// virtual tables are separated by '$' or '.'.
+func (d *decoder) vtableClass() (string, error) {
+	var names []string
+	for {
+		var name string
+		var err error
+		if isDigit(d.peek()) || d.peek() == 'Q' || d.peek() == 't' {
+			name, _, err = d.className()
+		} else {
+			// Unmangled class name.
+			end := strings.IndexAny(d.rest(), "$.")
+			if end == -1 {
+				end = len(d.rest())
+			}
+			name = d.rest()[:end]
+			d.pos += end
+		}
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if len(name) == 0 {
+			return "", errors.New("empty virtual table class name")
+		}
+		names = append(names, name)
+		if d.eof() {
+			return strings.Join(names, " in "), nil
+		}
+		if c := d.next(); c != '$' && c != '.' {
+			return "", errors.Errorf("invalid virtual table separator %q", c)
+		}
+	}
+}
+
+// params decodes a parameter list, up to the end of input or the '_'
+// terminating the parameters of function types.
+func (d *decoder) params() (params []*typ, variadic bool, err error) {
+	for !d.eof() && d.peek() != '_' {
+		switch d.peek() {
+		case 'e':
+			// Ellipsis.
+			d.next()
+			variadic = true
+			continue
+		case 'v':
+			// Empty parameter list.
+			if len(params) == 0 {
+				d.next()
+				continue
+			}
+		case 'T':
+			// Repeat remembered type.
+			d.next()
+			i, err := d.count()
+			if err != nil {
+				return nil, false, errors.WithStack(err)
+			}
+			if i >= len(d.types) {
+				return nil, false, errors.Errorf("invalid type reference %d", i)
+			}
+			t := d.types[i]
+			params = append(params, t)
+			d.types = append(d.types, t)
+			continue
+		case 'N':
+			// Repeat remembered type n times.
+			d.next()
+			n, err := d.count()
+			if err != nil {
+				return nil, false, errors.WithStack(err)
+			}
+			i, err := d.count()
+			if err != nil {
+				return nil, false, errors.WithStack(err)
+			}
+			if i >= len(d.types) {
+				return nil, false, errors.Errorf("invalid type reference %d", i)
+			}
+			t := d.types[i]
+			for j := 0; j < n; j++ {
+				params = append(params, t)
+				d.types = append(d.types, t)
+			}
+			continue
+		}
+		t, err := d.typ()
+		if err != nil {
+			return nil, false, errors.WithStack(err)
+		}
+		params = append(params, t)
+		d.types = append(d.types, t)
+	}
+	return params, variadic, nil
+}
+
+// builtins maps from builtin type code to type name.
+var builtins = map[byte]string{
+	'v': "void",
+	'c': "char",
+	's': "short",
+	'i': "int",
+	'l': "long",
+	'x': "long long",
+	'f': "float",
+	'd': "double",
+	'r': "long double",
+	'b': "bool",
+	'w': "wchar_t",
+}
+
+// typ decodes a type.
+func (d *decoder) typ() (*typ, error) {
+	var quals []string
+	for {
+		switch d.peek() {
+		case 'C':
+			quals = append(quals, "const")
+		case 'V':
+			quals = append(quals, "volatile")
+		case 'U':
+			quals = append(quals, "unsigned")
+		case 'S':
+			quals = append(quals, "signed")
+		default:
+			t, err := d.unqualifiedType()
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			for _, qual := range quals {
+				switch qual {
+				case "unsigned", "signed":
+					if t.kind != kindBase {
+						return nil, errors.Errorf("invalid %s modifier of non-builtin type", qual)
+					}
+					t.name = qual + " " + t.name
+				default:
+					t.quals = append(t.quals, qual)
+				}
+			}
+			return t, nil
+		}
+		d.next()
+	}
+}
+
+// unqualifiedType decodes a type without qualifiers.
+func (d *decoder) unqualifiedType() (*typ, error) {
+	c := d.peek()
+	if name, ok := builtins[c]; ok {
+		d.next()
+		return &typ{kind: kindBase, name: name}, nil
+	}
+	switch {
+	case isDigit(c) || c == 'Q' || c == 't':
+		name, _, err := d.className()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &typ{kind: kindBase, name: name}, nil
+	}
+	d.next()
+	switch c {
+	case 'P', 'R':
+		elem, err := d.typ()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		kind := kindPointer
+		if c == 'R' {
+			kind = kindReference
+		}
+		return &typ{kind: kind, elem: elem}, nil
+	case 'A':
+		n, err := d.number()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if d.next() != '_' {
+			return nil, errors.Errorf("expected '_' after array length %d", n)
+		}
+		elem, err := d.typ()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &typ{kind: kindArray, elem: elem, len: n}, nil
+	case 'F':
+		params, variadic, err := d.params()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if d.next() != '_' {
+			return nil, errors.New("expected '_' after function parameters")
+		}
+		ret, err := d.typ()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &typ{kind: kindFunc, elem: ret, params: params, variadic: variadic}, nil
+	case 'M', 'O':
+		// Pointer to member ('O' is used by some versions for data members).
+		class, _, err := d.className()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		t := &typ{kind: kindMember, class: class}
+		// Qualifiers of member function.
+		for d.peek() == 'C' || d.peek() == 'V' {
+			if d.next() == 'C' {
+				t.quals = append(t.quals, "const")
+			} else {
+				t.quals = append(t.quals, "volatile")
+			}
+		}
+		elem, err := d.typ()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		t.elem = elem
+		return t, nil
+	default:
+		return nil, errors.Errorf("support for type code %q not yet implemented", c)
+	}
+}
+
+// isDigit reports whether the given character is a decimal digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// ### [ Types ] ###############################################################
+
+// typeKind specifies the kind of a demangled type.
+type typeKind uint8
+
+// Type kinds.
+const (
+	kindBase typeKind = iota
+	kindPointer
+	kindReference
+	kindArray
+	kindFunc
+	kindMember
+)
+
+// typ is a demangled type.
+type typ struct {
+	// Type kind.
+	kind typeKind
+	// Name of base type.
+	name string
+	// Qualifiers (const, volatile).
+	quals []string
+	// Element type of pointers, references, arrays and member pointers; return
+	// type of functions.
+	elem *typ
+	// Array length.
+	len int
+	// Function parameters.
+	params []*typ
+	// Variadic function.
+	variadic bool
+	// Class of member pointers.
+	class string
+}
+
+// String returns the string representation of the type.
+func (t *typ) String() string {
+	return strings.TrimSpace(t.decl(""))
+}
+
+// decl returns the string representation of a declarator of the given type.
+func (t *typ) decl(inner string) string {
+	quals := strings.Join(t.quals, " ")
+	switch t.kind {
+	case kindPointer, kindReference:
+		ptr := "*"
+		if t.kind == kindReference {
+			ptr = "&"
+		}
+		if len(quals) > 0 {
+			ptr += " " + quals
+		}
+		s := ptr + inner
+		switch t.elem.kind {
+		case kindFunc, kindArray:
+			s = "(" + s + ")"
+		}
+		return t.elem.decl(s)
+	case kindMember:
+		s := t.class + "::*" + inner
+		if t.elem.kind == kindFunc {
+			ret := t.elem.elem.decl("(" + s + ")" + paramList(t.elem.params, t.elem.variadic))
+			if len(quals) > 0 {
+				ret += " " + quals
+			}
+			return ret
+		}
+		return t.elem.decl(s)
+	case kindArray:
+		return t.elem.decl(fmt.Sprintf("%s [%d]", inner, t.len))
+	case kindFunc:
+		return t.elem.decl(inner + paramList(t.params, t.variadic))
+	default:
+		s := t.name
+		if len(quals) > 0 {
+			s += " " + quals
+		}
+		if len(inner) > 0 {
+			s += " " + inner
+		}
+		return s
+	}
+}
+
+// paramList returns the string representation of the given parameter list.
+func paramList(params []*typ, variadic bool) string {
+	var ss []string
+	for _, param := range params {
+		ss = append(ss, param.String())
+	}
+	if variadic {
+		ss = append(ss, "...")
+	}
+	if len(ss) == 0 {
+		ss = append(ss, "void")
+	}
+	return "(" + strings.Join(ss, ", ") + ")"
+}
@@ -0,0 +1,62 @@
+package demangle_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/sym/demangle"
+)
+
+func TestDemangle(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		// Non-member functions.
+		{in: "foo__Fv", want: "foo(void)"},
+		{in: "foo__FiPc", want: "foo(int, char *)"},
+		{in: "foo__FUcUsUiUl", want: "foo(unsigned char, unsigned short, unsigned int, unsigned long)"},
+		{in: "foo__FPCce", want: "foo(char const *, ...)"},
+		{in: "foo__FiT0", want: "foo(int, int)"},
+		{in: "foo__FiN30", want: "foo(int, int, int, int)"},
+		{in: "foo__FPFi_v", want: "foo(void (*)(int))"},
+		{in: "foo__FPA10_i", want: "foo(int (*) [10])"},
+		{in: "do__it__Fi", want: "do__it(int)"},
+		// Member functions.
+		{in: "Add__5CListi", want: "CList::Add(int)"},
+		{in: "Get__C5CList", want: "CList::Get(void) const"},
+		{in: "Draw__Q26Screen6SpriteRC5CList", want: "Screen::Sprite::Draw(CList const &)"},
+		{in: "Push__t5Stack1ZiPi", want: "Stack<int>::Push(int *)"},
+		{in: "Set__5CListM5CListi", want: "CList::Set(int CList::*)"},
+		// Constructors and destructors.
+		{in: "__5CListi", want: "CList::CList(int)"},
+		{in: "__5CListRC5CList", want: "CList::CList(CList const &)"},
+		{in: "_$_5CList", want: "CList::~CList(void)"},
+		{in: "_._Q26Screen6Sprite", want: "Screen::Sprite::~Sprite(void)"},
+		// Operators.
+		{in: "__as__5CListRC5CList", want: "CList::operator=(CList const &)"},
+		{in: "__nw__FUi", want: "operator new(unsigned int)"},
+		{in: "__opi__5CList", want: "CList::operator int(void)"},
+		// Static data members and virtual tables.
+		{in: "_5CList$count", want: "CList::count"},
+		{in: "_vt$5CList", want: "CList virtual table"},
+		{in: "_GLOBAL_$I$main", want: "global constructors keyed to main"},
+	}
+	for _, g := range golden {
+		got, err := demangle.Demangle(g.in)
+		if err != nil {
+			t.Errorf("unable to demangle %q; %v", g.in, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%q: output mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestDemangleInvalid(t *testing.T) {
+	for _, in := range []string{"main", "_foo", "foo__", "foo__Fi_"} {
+		if got, err := demangle.Demangle(in); err == nil {
+			t.Errorf("%q: expected error, got %q", in, got)
+		}
+	}
+}
@@ -0,0 +1,513 @@
+package symelf
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// DWARF attribute forms.
+const (
+	formAddr   = 0x01
+	formData4  = 0x06
+	formString = 0x08
+	formBlock1 = 0x0A
+	formData1  = 0x0B
+	formFlag   = 0x0C
+	formSdata  = 0x0D
+	formRef4   = 0x13
+)
+
+// DWARF base type encodings.
+const (
+	ateSigned       = 0x05
+	ateSignedChar   = 0x06
+	ateUnsigned     = 0x07
+	ateUnsignedChar = 0x08
+)
+
+// DWARF location expression operations.
+const (
+	opAddr       = 0x03
+	opPlusUconst = 0x23
+	opReg0       = 0x50
+	opBreg0      = 0x70
+	opFbreg      = 0x91
+)
+
+// DWARF source language of compilation units.
+const langC89 = 0x0001
+
+// DWARF version of compilation units.
+const dwarfVersion = 2
+
+// Producer of compilation units.
+const producer = "sym_dump"
+
+// ### [ Debugging information entries ] #######################################
+
+// A die is a debugging information entry.
+type die struct {
+	// Tag of the entry.
+	tag dwarf.Tag
+	// Attributes of the entry.
+	attrs []*attr
+	// Child entries.
+	children []*die
+	// Abbreviation code; assigned during layout.
+	code uint64
+	// Offset in .debug_info; assigned during layout.
+	offset uint32
+}
+
+// An attr is an attribute of a debugging information entry.
+type attr struct {
+	// Attribute name.
+	attr dwarf.Attr
+	// Attribute form.
+	form uint8
+	// Attribute value; one of uint32, int64, string, bool, []byte and *die.
+	val interface{}
+}
+
+// add adds an attribute to the entry, and returns the entry.
+func (d *die) add(a dwarf.Attr, form uint8, val interface{}) *die {
+	d.attrs = append(d.attrs, &attr{attr: a, form: form, val: val})
+	return d
+}
+
+// child adds a child entry of the given tag to the entry.
+func (d *die) child(tag dwarf.Tag) *die {
+	child := &die{tag: tag}
+	d.children = append(d.children, child)
+	return child
+}
+
+// abbrevKey returns the key of the abbreviation of the entry; i.e. its tag,
+// child flag and attribute specifications.
+func (d *die) abbrevKey() string {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%d:%t", d.tag, len(d.children) > 0)
+	for _, a := range d.attrs {
+		fmt.Fprintf(buf, ":%d/%d", a.attr, a.form)
+	}
+	return buf.String()
+}
+
+// size returns the size in bytes of the attribute value.
+func (a *attr) size() uint32 {
+	switch a.form {
+	case formAddr, formData4, formRef4:
+		return 4
+	case formData1, formFlag:
+		return 1
+	case formString:
+		return uint32(len(a.val.(string))) + 1
+	case formBlock1:
+		return 1 + uint32(len(a.val.([]byte)))
+	case formSdata:
+		return uint32(len(appendSleb(nil, a.val.(int64))))
+	}
+	panic(fmt.Errorf("support for attribute form 0x%02X not yet implemented", a.form))
+}
+
+// ### [ .debug_info and .debug_abbrev ] #######################################
+
+// infoEncoder encodes the compilation units of .debug_info, and their
+// abbreviations of .debug_abbrev.
+type infoEncoder struct {
+	// Abbreviation table, shared by all compilation units.
+	abbrev buffer
+	// abbrevCodes maps from abbreviation key to abbreviation code.
+	abbrevCodes map[string]uint64
+	// Compilation units.
+	info buffer
+	// Offset of the compilation unit being encoded.
+	unitStart uint32
+}
+
+// newInfoEncoder returns a new .debug_info encoder.
+func newInfoEncoder() *infoEncoder {
+	return &infoEncoder{abbrevCodes: make(map[string]uint64)}
+}
+
+// encodeUnit encodes the compilation unit with the given root entry.
+func (enc *infoEncoder) encodeUnit(root *die) {
+	start := uint32(enc.info.Len())
+	enc.unitStart = start
+	// unit_length, version, debug_abbrev_offset, address_size.
+	const hdrSize = 4 + 2 + 4 + 1
+	end := enc.layout(root, start+hdrSize)
+	enc.info.u32(end - start - 4)
+	enc.info.u16(dwarfVersion)
+	enc.info.u32(0)
+	enc.info.u8(4)
+	enc.encodeDIE(root)
+}
+
+// layout assigns abbreviation codes and offsets to the given entry and its
+// children, and returns the offset following the entry.
+func (enc *infoEncoder) layout(d *die, offset uint32) uint32 {
+	key := d.abbrevKey()
+	code, ok := enc.abbrevCodes[key]
+	if !ok {
+		code = uint64(len(enc.abbrevCodes) + 1)
+		enc.abbrevCodes[key] = code
+		enc.encodeAbbrev(d, code)
+	}
+	d.code = code
+	d.offset = offset
+	offset += uint32(len(appendUleb(nil, code)))
+	for _, a := range d.attrs {
+		offset += a.size()
+	}
+	if len(d.children) == 0 {
+		return offset
+	}
+	for _, child := range d.children {
+		offset = enc.layout(child, offset)
+	}
+	// Null entry terminating the sibling chain.
+	return offset + 1
+}
+
+// encodeAbbrev encodes the abbreviation of the given entry.
+func (enc *infoEncoder) encodeAbbrev(d *die, code uint64) {
+	enc.abbrev.uleb(code)
+	enc.abbrev.uleb(uint64(d.tag))
+	if len(d.children) > 0 {
+		enc.abbrev.u8(1) // DW_CHILDREN_yes
+	} else {
+		enc.abbrev.u8(0) // DW_CHILDREN_no
+	}
+	for _, a := range d.attrs {
+		enc.abbrev.uleb(uint64(a.attr))
+		enc.abbrev.uleb(uint64(a.form))
+	}
+	enc.abbrev.uleb(0)
+	enc.abbrev.uleb(0)
+}
+
+// encodeDIE encodes the given entry and its children.
+func (enc *infoEncoder) encodeDIE(d *die) {
+	enc.info.uleb(d.code)
+	for _, a := range d.attrs {
+		switch a.form {
+		case formAddr, formData4:
+			enc.info.u32(a.val.(uint32))
+		case formData1:
+			enc.info.u8(uint8(a.val.(uint32)))
+		case formFlag:
+			if a.val.(bool) {
+				enc.info.u8(1)
+			} else {
+				enc.info.u8(0)
+			}
+		case formString:
+			enc.info.str(a.val.(string))
+		case formBlock1:
+			block := a.val.([]byte)
+			enc.info.u8(uint8(len(block)))
+			enc.info.Write(block)
+		case formSdata:
+			enc.info.sleb(a.val.(int64))
+		case formRef4:
+			// DW_FORM_ref4 is relative to the start of the compilation unit.
+			ref := a.val.(*die)
+			enc.info.u32(ref.offset - enc.unitStart)
+		}
+	}
+	if len(d.children) == 0 {
+		return
+	}
+	for _, child := range d.children {
+		enc.encodeDIE(child)
+	}
+	enc.info.u8(0)
+}
+
+// ### [ Compilation units ] ###################################################
+
+// A unit tracks the type entries of a compilation unit.
+type unit struct {
+	// Root entry of the compilation unit.
+	root *die
+	// types maps from type to type entry.
+	types map[c.Type]*die
+}
+
+// newUnit returns a new compilation unit of the given name, with type entries
+// for the named types recorded by the parser.
+func newUnit(p *csym.Parser, name string, lowPC, highPC, stmtList uint32) *unit {
+	root := &die{tag: dwarf.TagCompileUnit}
+	root.add(dwarf.AttrName, formString, name)
+	root.add(dwarf.AttrProducer, formString, producer)
+	root.add(dwarf.AttrLanguage, formData1, uint32(langC89))
+	root.add(dwarf.AttrLowpc, formAddr, lowPC)
+	root.add(dwarf.AttrHighpc, formAddr, highPC)
+	root.add(dwarf.AttrStmtList, formData4, stmtList)
+	u := &unit{root: root, types: make(map[c.Type]*die)}
+	// Named types, in order of occurrence in the SYM file.
+	for _, tag := range p.StructTags {
+		u.typeDIE(p.Structs[tag])
+	}
+	for _, tag := range p.UnionTags {
+		u.typeDIE(p.Unions[tag])
+	}
+	for _, tag := range p.EnumTags {
+		u.typeDIE(p.Enums[tag])
+	}
+	for _, def := range p.Typedefs {
+		u.typeDIE(def)
+	}
+	return u
+}
+
+// addType adds a DW_AT_type attribute referring to the given type to the
+// entry. No attribute is added for the void type.
+func (u *unit) addType(d *die, t c.Type) {
+	if ref := u.typeDIE(t); ref != nil {
+		d.add(dwarf.AttrType, formRef4, ref)
+	}
+}
+
+// typeDIE returns the type entry of the given type, creating it if not yet
+// present. The nil entry is returned for the void type.
+func (u *unit) typeDIE(t c.Type) *die {
+	if t == nil || t == c.Void {
+		return nil
+	}
+	if t, ok := t.(*c.TagDecl); ok {
+		// Tag declarations refer to the declared type.
+		return u.typeDIE(t.Type)
+	}
+	if d, ok := u.types[t]; ok {
+		return d
+	}
+	d := &die{}
+	// Note, the type entry is recorded before its children are added, to
+	// handle self-referential types.
+	u.types[t] = d
+	u.root.children = append(u.root.children, d)
+	switch t := t.(type) {
+	case c.BaseType:
+		size, enc := baseTypeInfo(t)
+		d.tag = dwarf.TagBaseType
+		d.add(dwarf.AttrName, formString, t.String())
+		d.add(dwarf.AttrEncoding, formData1, uint32(enc))
+		d.add(dwarf.AttrByteSize, formData1, size)
+	case *c.PointerType:
+		d.tag = dwarf.TagPointerType
+		d.add(dwarf.AttrByteSize, formData1, uint32(4))
+		u.addType(d, t.Elem)
+	case *c.StructType:
+		d.tag = dwarf.TagStructType
+		u.addAggregate(d, t.Tag, t.Size, t.Fields)
+	case *c.UnionType:
+		d.tag = dwarf.TagUnionType
+		u.addAggregate(d, t.Tag, t.Size, t.Fields)
+	case *c.EnumType:
+		d.tag = dwarf.TagEnumerationType
+		if !c.IsFakeTag(t.Tag) {
+			d.add(dwarf.AttrName, formString, t.Tag)
+		}
+		size := t.Size
+		if size == 0 {
+			size = 4
+		}
+		d.add(dwarf.AttrByteSize, formData1, size)
+		for _, member := range t.Members {
+			m := d.child(dwarf.TagEnumerator)
+			m.add(dwarf.AttrName, formString, member.Name)
+			m.add(dwarf.AttrConstValue, formSdata, int64(member.Value))
+		}
+	case *c.ArrayType:
+		d.tag = dwarf.TagArrayType
+		u.addType(d, t.Elem)
+		subrange := d.child(dwarf.TagSubrangeType)
+		if t.Len > 0 {
+			subrange.add(dwarf.AttrUpperBound, formData4, uint32(t.Len-1))
+		}
+	case *c.FuncType:
+		d.tag = dwarf.TagSubroutineType
+		d.add(dwarf.AttrPrototyped, formFlag, t.Prototyped || len(t.Params) > 0)
+		u.addType(d, t.RetType)
+		for _, param := range t.Params {
+			u.addType(d.child(dwarf.TagFormalParameter), param.Type)
+		}
+		if t.Variadic {
+			d.child(dwarf.TagUnspecifiedParameters)
+		}
+	case *c.TypedefType:
+		d.tag = dwarf.TagTypedef
+		d.add(dwarf.AttrName, formString, t.Name)
+		u.addType(d, t.Type)
+	case *c.VarDecl:
+		// Type definition.
+		d.tag = dwarf.TagTypedef
+		d.add(dwarf.AttrName, formString, t.Name)
+		u.addType(d, t.Type)
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", t))
+	}
+	return d
+}
+
+// addAggregate adds the name, size and members of a structure or union type to
+// the given type entry.
+func (u *unit) addAggregate(d *die, tag string, size uint32, fields []c.Field) {
+	if !c.IsFakeTag(tag) {
+		d.add(dwarf.AttrName, formString, tag)
+	}
+	if size == 0 && len(fields) == 0 {
+		d.add(dwarf.AttrDeclaration, formFlag, true)
+		return
+	}
+	d.add(dwarf.AttrByteSize, formData4, size)
+	for _, field := range fields {
+		m := d.child(dwarf.TagMember)
+		if len(field.Name) > 0 {
+			m.add(dwarf.AttrName, formString, field.Name)
+		}
+		u.addType(m, field.Type)
+		if d.tag == dwarf.TagStructType {
+			loc := appendUleb([]byte{opPlusUconst}, uint64(field.Offset))
+			m.add(dwarf.AttrDataMemberLoc, formBlock1, loc)
+		}
+	}
+}
+
+// baseTypeInfo returns the size in bytes and DWARF encoding of the given base
+// type.
+func baseTypeInfo(t c.BaseType) (size uint32, enc uint8) {
+	switch t {
+	case c.Char:
+		return 1, ateSignedChar
+	case c.Short:
+		return 2, ateSigned
+	case c.Int, c.Long:
+		return 4, ateSigned
+	case c.UChar:
+		return 1, ateUnsignedChar
+	case c.UShort:
+		return 2, ateUnsigned
+	case c.UInt, c.ULong:
+		return 4, ateUnsigned
+	}
+	panic(fmt.Errorf("support for base type %v not yet implemented", t))
+}
+
+// addVar adds a global variable entry to the compilation unit.
+func (u *unit) addVar(v *c.VarDecl) {
+	d := u.root.child(dwarf.TagVariable)
+	d.add(dwarf.AttrName, formString, v.Name)
+	u.addType(d, v.Type)
+	d.add(dwarf.AttrExternal, formFlag, v.Class != c.Static)
+	d.add(dwarf.AttrLocation, formBlock1, location(v.Loc))
+}
+
+// addFunc adds a function entry to the compilation unit, with entries for its
+// parameters, local variables and lexical blocks. External specifies whether
+// the function is visible outside of its source file.
+func (u *unit) addFunc(f *c.FuncDecl, highPC uint32, external bool) {
+	d := u.root.child(dwarf.TagSubprogram)
+	d.add(dwarf.AttrName, formString, f.Name)
+	d.add(dwarf.AttrExternal, formFlag, external)
+	d.add(dwarf.AttrLowpc, formAddr, f.Addr)
+	d.add(dwarf.AttrHighpc, formAddr, highPC)
+	if f.Frame != nil {
+		fp := appendSleb([]byte{opBreg0 + uint8(f.Frame.FP)}, 0)
+		d.add(dwarf.AttrFrameBase, formBlock1, fp)
+	}
+	funcType, ok := f.Type.(*c.FuncType)
+	if !ok {
+		return
+	}
+	d.add(dwarf.AttrPrototyped, formFlag, funcType.Prototyped || len(funcType.Params) > 0)
+	u.addType(d, funcType.RetType)
+	for _, param := range funcType.Params {
+		u.addLocal(d, dwarf.TagFormalParameter, param)
+	}
+	if funcType.Variadic {
+		d.child(dwarf.TagUnspecifiedParameters)
+	}
+	// The outermost block is the function body, the locals of which are
+	// children of the function entry.
+	blocks := f.Blocks
+	if len(blocks) == 1 {
+		for _, local := range blocks[0].Locals {
+			u.addLocal(d, dwarf.TagVariable, local)
+		}
+		blocks = blocks[0].Blocks
+	}
+	for _, block := range blocks {
+		u.addBlock(d, block)
+	}
+}
+
+// addBlock adds a lexical block entry to the given parent entry, with entries
+// for its local variables and nested blocks.
+func (u *unit) addBlock(parent *die, block *c.Block) {
+	d := parent.child(dwarf.TagLexDwarfBlock)
+	d.add(dwarf.AttrLowpc, formAddr, block.Addr)
+	d.add(dwarf.AttrHighpc, formAddr, block.EndAddr)
+	for _, local := range block.Locals {
+		u.addLocal(d, dwarf.TagVariable, local)
+	}
+	for _, child := range block.Blocks {
+		u.addBlock(d, child)
+	}
+}
+
+// addLocal adds a parameter or local variable entry to the given parent entry.
+func (u *unit) addLocal(parent *die, tag dwarf.Tag, v *c.VarDecl) {
+	d := parent.child(tag)
+	if len(v.Name) > 0 {
+		d.add(dwarf.AttrName, formString, v.Name)
+	}
+	u.addType(d, v.Type)
+	if v.Loc != nil {
+		d.add(dwarf.AttrLocation, formBlock1, location(v.Loc))
+	}
+}
+
+// location returns the DWARF location expression of the given storage
+// location.
+func location(loc c.Location) []byte {
+	switch loc := loc.(type) {
+	case *c.StaticLoc:
+		return appendU32([]byte{opAddr}, loc.Addr)
+	case *c.StackLoc:
+		return appendSleb([]byte{opFbreg}, int64(loc.Offset))
+	case *c.RegLoc:
+		return []byte{opReg0 + uint8(loc.Reg)}
+	}
+	panic(fmt.Errorf("support for location %T not yet implemented", loc))
+}
+
+// funcEnds returns the end address of each function of the overlay. The end
+// address of functions of unknown size is the start address of the succeeding
+// function.
+func funcEnds(overlay *csym.Overlay) map[*c.FuncDecl]uint32 {
+	funcs := make([]*c.FuncDecl, len(overlay.Funcs))
+	copy(funcs, overlay.Funcs)
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].Addr < funcs[j].Addr
+	})
+	ends := make(map[*c.FuncDecl]uint32)
+	for i, f := range funcs {
+		switch {
+		case f.Size > 0:
+			ends[f] = f.Addr + f.Size
+		case i+1 < len(funcs) && funcs[i+1].Addr > f.Addr:
+			ends[f] = funcs[i+1].Addr
+		default:
+			ends[f] = f.Addr + 4
+		}
+	}
+	return ends
+}
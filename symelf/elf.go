@@ -0,0 +1,356 @@
+// Package symelf writes Playstation 1 symbol information as a MIPS ELF file with
+// DWARF debug information.
+package symelf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// A Text is the loaded code and data of an executable.
+type Text struct {
+	// Load address.
+	Addr uint32
+	// Contents.
+	Data []byte
+	// Entry point.
+	Entry uint32
+}
+
+// PS-X EXE header.
+const (
+	// Size of PS-X EXE header in bytes.
+	exeHdrSize = 0x800
+	// PS-X EXE signature.
+	exeSignature = "PS-X EXE"
+)
+
+// ParseEXE parses the given PS-X EXE executable, and returns its loaded code
+// and data.
+func ParseEXE(b []byte) (*Text, error) {
+	if len(b) < exeHdrSize || !bytes.HasPrefix(b, []byte(exeSignature)) {
+		return nil, errors.Errorf("invalid PS-X EXE signature; expected %q", exeSignature)
+	}
+	// Header fields.
+	//
+	//	0x10  pc0     entry point
+	//	0x18  t_addr  load address
+	//	0x1C  t_size  size of code and data
+	le := binary.LittleEndian
+	text := &Text{
+		Entry: le.Uint32(b[0x10:]),
+		Addr:  le.Uint32(b[0x18:]),
+	}
+	size := le.Uint32(b[0x1C:])
+	if uint64(exeHdrSize)+uint64(size) > uint64(len(b)) {
+		return nil, errors.Errorf("invalid PS-X EXE text size 0x%X; exceeds file size 0x%X", size, len(b))
+	}
+	text.Data = b[exeHdrSize : exeHdrSize+size]
+	return text, nil
+}
+
+// Section indices of the ELF file.
+const (
+	shText = iota + 1
+	shSymtab
+	shStrtab
+	shDebugAbbrev
+	shDebugInfo
+	shDebugLine
+	shDebugFrame
+	shShstrtab
+	shCount
+)
+
+// Write writes the declarations and source line numbers recorded by the parser
+// as a MIPS little-endian ELF executable to w.
+//
+// Functions and global variables are written to .symtab; types, functions,
+// parameters, local variables and lexical blocks to .debug_info (one
+// compilation unit per overlay); source line numbers to .debug_line; and stack
+// frame layouts to .debug_frame, for functions with a prologue recognized in
+// text. The code and data of the executable are optionally taken from text;
+// otherwise the .text section holds no data.
+func Write(w io.Writer, p *csym.Parser, text *Text) error {
+	overlays := append([]*csym.Overlay{p.Overlay}, p.Overlays...)
+	// Debug information.
+	info := newInfoEncoder()
+	lineBuf, frameBuf := &buffer{}, &buffer{}
+	ends := make(map[*c.FuncDecl]uint32)
+	exported := p.ExportedFuncs()
+	for _, overlay := range overlays {
+		overlayEnds := funcEnds(overlay)
+		for f, end := range overlayEnds {
+			ends[f] = end
+		}
+		var lowPC, highPC uint32
+		for i, f := range overlay.Funcs {
+			if i == 0 || f.Addr < lowPC {
+				lowPC = f.Addr
+			}
+			if end := overlayEnds[f]; end > highPC {
+				highPC = end
+			}
+		}
+		stmtList := encodeLines(lineBuf, overlay.Lines)
+		u := newUnit(p, unitName(overlay), lowPC, highPC, stmtList)
+		for _, v := range overlay.Vars {
+			u.addVar(v)
+		}
+		for _, f := range overlay.Funcs {
+			u.addFunc(f, overlayEnds[f], exported[f])
+		}
+		info.encodeUnit(u.root)
+		// The code of overlays is not part of the executable.
+		var code *Text
+		if overlay.ID == 0 {
+			code = text
+		}
+		encodeFrames(frameBuf, overlay.Funcs, overlayEnds, code)
+	}
+	// Null entry terminating the abbreviation table.
+	info.abbrev.uleb(0)
+	// Text section. Without code and data, the section only describes the
+	// address range of the functions and global variables of the default
+	// binary.
+	textType := elf.SHT_PROGBITS
+	var textSize uint32
+	if text != nil {
+		textSize = uint32(len(text.Data))
+	} else {
+		textType = elf.SHT_NOBITS
+		text = &Text{}
+		var end uint32
+		text.Addr, end = textRange(p.Overlay, ends)
+		textSize = end - text.Addr
+	}
+	// Symbol table.
+	symtab, strtab, firstGlobal := encodeSymtab(overlays, ends, exported, text.Addr, textSize)
+	// Section contents and headers.
+	shstrtab := &buffer{}
+	shstrtab.u8(0)
+	sects := make([]elf.Section32, shCount)
+	contents := make([][]byte, shCount)
+	addSect := func(idx int, name string, typ elf.SectionType, data []byte) {
+		sects[idx].Name = uint32(shstrtab.Len())
+		shstrtab.str(name)
+		sects[idx].Type = uint32(typ)
+		sects[idx].Addralign = 1
+		contents[idx] = data
+	}
+	addSect(shText, ".text", textType, text.Data)
+	sects[shText].Flags = uint32(elf.SHF_ALLOC | elf.SHF_EXECINSTR | elf.SHF_WRITE)
+	sects[shText].Addr = text.Addr
+	sects[shText].Addralign = 4
+	addSect(shSymtab, ".symtab", elf.SHT_SYMTAB, symtab)
+	sects[shSymtab].Link = shStrtab
+	sects[shSymtab].Info = firstGlobal
+	sects[shSymtab].Entsize = uint32(binary.Size(elf.Sym32{}))
+	sects[shSymtab].Addralign = 4
+	addSect(shStrtab, ".strtab", elf.SHT_STRTAB, strtab)
+	addSect(shDebugAbbrev, ".debug_abbrev", elf.SHT_PROGBITS, info.abbrev.Bytes())
+	addSect(shDebugInfo, ".debug_info", elf.SHT_PROGBITS, info.info.Bytes())
+	addSect(shDebugLine, ".debug_line", elf.SHT_PROGBITS, lineBuf.Bytes())
+	addSect(shDebugFrame, ".debug_frame", elf.SHT_PROGBITS, frameBuf.Bytes())
+	addSect(shShstrtab, ".shstrtab", elf.SHT_STRTAB, nil)
+	// Note, the section header string table is complete once all sections
+	// have been added.
+	contents[shShstrtab] = shstrtab.Bytes()
+	// Layout.
+	hdrSize := uint32(binary.Size(elf.Header32{}))
+	progSize := uint32(binary.Size(elf.Prog32{}))
+	offset := hdrSize + progSize
+	for idx := 1; idx < shCount; idx++ {
+		offset = align(offset, sects[idx].Addralign)
+		sects[idx].Off = offset
+		sects[idx].Size = uint32(len(contents[idx]))
+		offset += sects[idx].Size
+	}
+	sects[shText].Size = textSize
+	shoff := align(offset, 4)
+	// ELF header.
+	hdr := elf.Header32{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_MIPS),
+		Version:   uint32(elf.EV_CURRENT),
+		Entry:     text.Entry,
+		Phoff:     hdrSize,
+		Shoff:     shoff,
+		Ehsize:    uint16(hdrSize),
+		Phentsize: uint16(progSize),
+		Phnum:     1,
+		Shentsize: uint16(binary.Size(elf.Section32{})),
+		Shnum:     shCount,
+		Shstrndx:  shShstrtab,
+	}
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS32)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	// Program header of the loaded code and data.
+	prog := elf.Prog32{
+		Type:   uint32(elf.PT_LOAD),
+		Off:    sects[shText].Off,
+		Vaddr:  text.Addr,
+		Paddr:  text.Addr,
+		Filesz: uint32(len(text.Data)),
+		Memsz:  textSize,
+		Flags:  uint32(elf.PF_R | elf.PF_W | elf.PF_X),
+		Align:  4,
+	}
+	// Output.
+	out := &buffer{}
+	le := binary.LittleEndian
+	if err := binary.Write(out, le, &hdr); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := binary.Write(out, le, &prog); err != nil {
+		return errors.WithStack(err)
+	}
+	for idx := 1; idx < shCount; idx++ {
+		for uint32(out.Len()) < sects[idx].Off {
+			out.u8(0)
+		}
+		out.Write(contents[idx])
+	}
+	for uint32(out.Len()) < shoff {
+		out.u8(0)
+	}
+	if err := binary.Write(out, le, sects); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// unitName returns the compilation unit name of the given overlay.
+func unitName(overlay *csym.Overlay) string {
+	if overlay.ID == 0 {
+		return "main"
+	}
+	return fmt.Sprintf("overlay_%x", overlay.ID)
+}
+
+// textRange returns the address range of the functions and global variables
+// of the given overlay.
+func textRange(overlay *csym.Overlay, ends map[*c.FuncDecl]uint32) (start, end uint32) {
+	extend := func(addr, addrEnd uint32) {
+		if addr == 0 {
+			return
+		}
+		if start == 0 || addr < start {
+			start = addr
+		}
+		if addrEnd > end {
+			end = addrEnd
+		}
+	}
+	for _, f := range overlay.Funcs {
+		extend(f.Addr, ends[f])
+	}
+	for _, v := range overlay.Vars {
+		extend(v.Addr(), v.Addr()+v.Size)
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// encodeSymtab encodes the symbol table and string table of the functions,
+// global variables and symbols of the given overlays, and returns the index of
+// the first global symbol.
+//
+// Symbols of the default binary within the text section are associated with
+// .text; all other symbols are absolute. Static functions and variables are
+// local symbols, as are functions without linker symbol (see
+// Parser.ExportedFuncs).
+func encodeSymtab(overlays []*csym.Overlay, ends map[*c.FuncDecl]uint32, exported map[*c.FuncDecl]bool, textAddr, textSize uint32) (symtab, strtab []byte, firstGlobal uint32) {
+	strs := &buffer{}
+	strs.u8(0)
+	strIndex := make(map[string]uint32)
+	var locals, globals []elf.Sym32
+	add := func(overlay *csym.Overlay, name string, addr, size uint32, bind elf.SymBind, typ elf.SymType) {
+		idx, ok := strIndex[name]
+		if !ok {
+			idx = uint32(strs.Len())
+			strs.str(name)
+			strIndex[name] = idx
+		}
+		s := elf.Sym32{
+			Name:  idx,
+			Value: addr,
+			Size:  size,
+			Info:  elf.ST_INFO(bind, typ),
+			Shndx: uint16(elf.SHN_ABS),
+		}
+		if overlay.ID == 0 && textAddr <= addr && addr < textAddr+textSize {
+			s.Shndx = shText
+		}
+		if bind == elf.STB_LOCAL {
+			locals = append(locals, s)
+		} else {
+			globals = append(globals, s)
+		}
+	}
+	for _, overlay := range overlays {
+		addrs := make(map[uint32]bool)
+		for _, f := range overlay.Funcs {
+			addrs[f.Addr] = true
+			bind := elf.STB_GLOBAL
+			if !exported[f] {
+				bind = elf.STB_LOCAL
+			}
+			add(overlay, f.Name, f.Addr, ends[f]-f.Addr, bind, elf.STT_FUNC)
+		}
+		for _, v := range overlay.Vars {
+			addrs[v.Addr()] = true
+			bind := elf.STB_GLOBAL
+			if v.Class == c.Static {
+				bind = elf.STB_LOCAL
+			}
+			add(overlay, v.Name, v.Addr(), v.Size, bind, elf.STT_OBJECT)
+		}
+		// Symbols without type information.
+		var syms []*csym.Symbol
+		for _, s := range overlay.AddrSymbols() {
+			if addrs[s.Addr] {
+				continue
+			}
+			addrs[s.Addr] = true
+			syms = append(syms, s)
+		}
+		sort.SliceStable(syms, func(i, j int) bool {
+			return syms[i].Addr < syms[j].Addr
+		})
+		for _, s := range syms {
+			add(overlay, s.Name, s.Addr, 0, elf.STB_GLOBAL, elf.STT_NOTYPE)
+		}
+	}
+	// Local symbols precede global symbols, following the NULL symbol.
+	syms := append([]elf.Sym32{{}}, locals...)
+	syms = append(syms, globals...)
+	out := &bytes.Buffer{}
+	if err := binary.Write(out, binary.LittleEndian, syms); err != nil {
+		panic(fmt.Errorf("unable to encode symbol table; %v", err))
+	}
+	return out.Bytes(), strs.Bytes(), uint32(1 + len(locals))
+}
+
+// align returns the given offset rounded up to a multiple of n.
+func align(offset, n uint32) uint32 {
+	if n <= 1 {
+		return offset
+	}
+	return (offset + n - 1) &^ (n - 1)
+}
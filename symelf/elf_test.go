@@ -0,0 +1,230 @@
+package symelf_test
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+	"github.com/sanctuary/sym/symelf"
+)
+
+func TestWrite(t *testing.T) {
+	// struct node { struct node *next; int val; };
+	node := &c.StructType{Size: 8, Tag: "node"}
+	node.Fields = []c.Field{
+		{Offset: 0, Size: 4, Var: c.Var{Type: &c.PointerType{Elem: node}, Name: "next"}},
+		{Offset: 4, Size: 4, Var: c.Var{Type: c.Int, Name: "val"}},
+	}
+	p := csym.NewParser()
+	p.Structs["node"] = node
+	p.StructTags = append(p.StructTags, "node")
+	// struct node *head;
+	head := &c.VarDecl{
+		Loc:   &c.StaticLoc{Addr: 0x80010000},
+		Size:  4,
+		Class: c.Extern,
+		Var:   c.Var{Type: &c.PointerType{Elem: node}, Name: "head"},
+	}
+	// int sum(struct node *n) { int total; ... }
+	n := &c.VarDecl{
+		Loc:   &c.RegLoc{Reg: 4},
+		Class: c.Register,
+		Var:   c.Var{Type: &c.PointerType{Elem: node}, Name: "n"},
+	}
+	total := &c.VarDecl{
+		Loc:   &c.StackLoc{Offset: 0x10},
+		Class: c.Auto,
+		Var:   c.Var{Type: c.Int, Name: "total"},
+	}
+	sum := &c.FuncDecl{
+		Path:      "main.c",
+		Addr:      0x80020000,
+		Size:      0x40,
+		LineStart: 10,
+		LineEnd:   20,
+		Var: c.Var{
+			Type: &c.FuncType{RetType: c.Int, Params: []*c.VarDecl{n}},
+			Name: "sum",
+		},
+		Frame: &c.Frame{
+			FP:     29,
+			Size:   0x18,
+			RetReg: 31,
+			Saved:  []*c.SavedReg{{Reg: 31, Offset: 0x14}},
+		},
+		Blocks: []*c.Block{
+			{Addr: 0x80020008, EndAddr: 0x80020038, LineStart: 10, LineEnd: 20, Locals: []*c.VarDecl{total}},
+		},
+	}
+	// static void helper(void) {}
+	helper := &c.FuncDecl{
+		Path:      "main.c",
+		Addr:      0x80020040,
+		Size:      0x8,
+		LineStart: 22,
+		LineEnd:   23,
+		Var:       c.Var{Type: &c.FuncType{RetType: c.Void}, Name: "helper"},
+		Frame:     &c.Frame{FP: 29, RetReg: 31},
+	}
+	p.Vars = append(p.Vars, head)
+	p.Funcs = append(p.Funcs, sum, helper)
+	p.Symbols = []*csym.Symbol{{Addr: 0x80020000, Name: "sum"}}
+	p.Lines = []*csym.Line{
+		{Addr: 0x80020000, Path: "main.c", Line: 10},
+		{Addr: 0x80020010, Path: "main.c", Line: 12},
+	}
+	// Code of sum and helper.
+	text := &symelf.Text{Addr: 0x80020000, Data: make([]byte, 0x48)}
+	binary.LittleEndian.PutUint32(text.Data[0:], 0x27BDFFE8) // addiu $sp, $sp, -0x18
+	binary.LittleEndian.PutUint32(text.Data[4:], 0xAFBF0014) // sw $ra, 0x14($sp)
+	buf := &bytes.Buffer{}
+	if err := symelf.Write(buf, p, text); err != nil {
+		t.Fatalf("unable to write ELF file; %+v", err)
+	}
+	f, err := elf.NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to parse ELF file; %v", err)
+	}
+	if f.Machine != elf.EM_MIPS || f.ByteOrder.String() != "LittleEndian" {
+		t.Errorf("ELF machine mismatch; expected MIPS little-endian, got %v %v", f.Machine, f.ByteOrder)
+	}
+	// Symbol table.
+	syms, err := f.Symbols()
+	if err != nil {
+		t.Fatalf("unable to parse symbol table; %v", err)
+	}
+	wantSyms := map[string]uint64{"sum": 0x80020000, "head": 0x80010000, "helper": 0x80020040}
+	wantBinds := map[string]elf.SymBind{"sum": elf.STB_GLOBAL, "head": elf.STB_GLOBAL, "helper": elf.STB_LOCAL}
+	for _, s := range syms {
+		if addr, ok := wantSyms[s.Name]; ok {
+			if s.Value != addr {
+				t.Errorf("symbol %q address mismatch; expected 0x%08X, got 0x%08X", s.Name, addr, s.Value)
+			}
+			if bind := elf.ST_BIND(s.Info); bind != wantBinds[s.Name] {
+				t.Errorf("symbol %q binding mismatch; expected %v, got %v", s.Name, wantBinds[s.Name], bind)
+			}
+			delete(wantSyms, s.Name)
+		}
+	}
+	for name := range wantSyms {
+		t.Errorf("symbol %q not found", name)
+	}
+	// Debug information.
+	d, err := f.DWARF()
+	if err != nil {
+		t.Fatalf("unable to parse DWARF; %v", err)
+	}
+	var names []string
+	r := d.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			t.Fatalf("unable to read DWARF entry; %v", err)
+		}
+		if e == nil {
+			break
+		}
+		if name, ok := e.Val(dwarf.AttrName).(string); ok {
+			names = append(names, e.Tag.String()+" "+name)
+		}
+		if e.Tag == dwarf.TagSubprogram {
+			name := e.Val(dwarf.AttrName).(string)
+			if got, want := e.Val(dwarf.AttrExternal) == true, name == "sum"; got != want {
+				t.Errorf("external attribute of %q mismatch; expected %v, got %v", name, want, got)
+			}
+		}
+		if e.Tag == dwarf.TagVariable && e.Val(dwarf.AttrName) == "head" {
+			typ, err := d.Type(e.Val(dwarf.AttrType).(dwarf.Offset))
+			if err != nil {
+				t.Fatalf("unable to read type of head; %v", err)
+			}
+			if got, want := typ.String(), "*struct node"; got != want {
+				t.Errorf("type of head mismatch; expected %q, got %q", want, got)
+			}
+		}
+	}
+	want := []string{
+		"CompileUnit main",
+		"StructType node",
+		"Member next",
+		"Member val",
+		"BaseType int",
+		"Variable head",
+		"Subprogram sum",
+		"FormalParameter n",
+		"Variable total",
+		"Subprogram helper",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("DWARF entries mismatch; expected %q, got %q", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("DWARF entry %d mismatch; expected %q, got %q", i, want[i], names[i])
+		}
+	}
+	// Line numbers.
+	cu, err := d.Reader().Next()
+	if err != nil {
+		t.Fatalf("unable to read compilation unit; %v", err)
+	}
+	lr, err := d.LineReader(cu)
+	if err != nil || lr == nil {
+		t.Fatalf("unable to read line number program; %v", err)
+	}
+	var lines []int
+	var entry dwarf.LineEntry
+	for lr.Next(&entry) == nil {
+		if !entry.EndSequence {
+			lines = append(lines, entry.Line)
+		}
+	}
+	if len(lines) != 2 || lines[0] != 10 || lines[1] != 12 {
+		t.Errorf("line numbers mismatch; expected [10 12], got %v", lines)
+	}
+	// Call frame information.
+	frame := f.Section(".debug_frame")
+	if frame == nil {
+		t.Fatalf("missing .debug_frame section")
+	}
+	data, err := frame.Data()
+	if err != nil {
+		t.Fatalf("unable to read .debug_frame section; %v", err)
+	}
+	// CIE and FDEs of sum and helper.
+	var fdes [][]byte
+	for len(data) >= 4 {
+		n := binary.LittleEndian.Uint32(data)
+		entry := data[4 : 4+n]
+		if binary.LittleEndian.Uint32(entry) != 0xFFFFFFFF {
+			fdes = append(fdes, entry)
+		}
+		data = data[4+n:]
+	}
+	if len(fdes) != 2 {
+		t.Fatalf("FDE count mismatch; expected 2, got %d", len(fdes))
+	}
+	// CFA = $sp + 0x18 after addiu; $ra at CFA - 4 after sw.
+	if insts := []byte{0x44, 0x0E, 0x18, 0x44, 0x9F, 0x01}; !bytes.HasPrefix(fdes[0][12:], insts) {
+		t.Errorf("FDE instructions of sum mismatch; expected prefix % X, got % X", insts, fdes[0][12:])
+	}
+	if pc := binary.LittleEndian.Uint32(fdes[1][4:]); pc != 0x80020040 {
+		t.Errorf("FDE address of helper mismatch; expected 0x80020040, got 0x%08X", pc)
+	}
+	// Without code, the prologue of functions is unknown.
+	buf.Reset()
+	if err := symelf.Write(buf, p, nil); err != nil {
+		t.Fatalf("unable to write ELF file; %+v", err)
+	}
+	f, err = elf.NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unable to parse ELF file; %v", err)
+	}
+	if data, err := f.Section(".debug_frame").Data(); err != nil || len(data) != 0 {
+		t.Errorf("unexpected call frame information without code; got % X (%v)", data, err)
+	}
+}
@@ -0,0 +1,351 @@
+package symelf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/sanctuary/sym/csym"
+	"github.com/sanctuary/sym/csym/c"
+)
+
+// ### [ .debug_line ] #########################################################
+
+// Line number program header parameters.
+const (
+	lineVersion    = 2
+	lineMinInstLen = 4
+	lineBase       = -5
+	lineRange      = 14
+	lineOpcodeBase = 10
+)
+
+// Standard opcodes of line number programs.
+const (
+	lnsCopy        = 0x01
+	lnsAdvanceLine = 0x03
+	lnsSetFile     = 0x04
+)
+
+// Extended opcodes of line number programs.
+const (
+	lneEndSequence = 0x01
+	lneSetAddress  = 0x02
+)
+
+// encodeLines encodes the line number program of the given source lines to
+// .debug_line, and returns its offset.
+func encodeLines(buf *buffer, lines []*csym.Line) uint32 {
+	start := uint32(buf.Len())
+	// Source files, in order of occurrence.
+	var paths []string
+	fileIndex := make(map[string]uint64)
+	for _, line := range lines {
+		if _, ok := fileIndex[line.Path]; !ok {
+			paths = append(paths, line.Path)
+			fileIndex[line.Path] = uint64(len(paths))
+		}
+	}
+	hdr := &buffer{}
+	hdr.u8(lineMinInstLen)
+	hdr.u8(1) // default_is_stmt
+	hdr.u8(lineBase & 0xFF)
+	hdr.u8(lineRange)
+	hdr.u8(lineOpcodeBase)
+	// Number of LEB128 operands of standard opcodes 1 through 9.
+	hdr.Write([]byte{0, 1, 1, 1, 1, 0, 0, 0, 1})
+	// Include directories.
+	hdr.u8(0)
+	// File names.
+	for _, path := range paths {
+		hdr.str(path)
+		hdr.uleb(0) // directory index
+		hdr.uleb(0) // modification time
+		hdr.uleb(0) // file length
+	}
+	hdr.u8(0)
+	// Line number program. Rows are emitted in address order, each with an
+	// explicit address, as lines of different overlays may interleave.
+	sorted := make([]*csym.Line, len(lines))
+	copy(sorted, lines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Addr < sorted[j].Addr
+	})
+	prog := &buffer{}
+	file, line := uint64(1), int64(1)
+	for _, l := range sorted {
+		if idx := fileIndex[l.Path]; idx != file {
+			prog.u8(lnsSetFile)
+			prog.uleb(idx)
+			file = idx
+		}
+		prog.u8(0)
+		prog.uleb(5)
+		prog.u8(lneSetAddress)
+		prog.u32(l.Addr)
+		if delta := int64(l.Line) - line; delta != 0 {
+			prog.u8(lnsAdvanceLine)
+			prog.sleb(delta)
+			line = int64(l.Line)
+		}
+		prog.u8(lnsCopy)
+	}
+	if len(sorted) > 0 {
+		prog.u8(0)
+		prog.uleb(1)
+		prog.u8(lneEndSequence)
+	}
+	// unit_length, version, header_length.
+	buf.u32(uint32(2 + 4 + hdr.Len() + prog.Len()))
+	buf.u16(lineVersion)
+	buf.u32(uint32(hdr.Len()))
+	buf.Write(hdr.Bytes())
+	buf.Write(prog.Bytes())
+	return start
+}
+
+// ### [ .debug_frame ] ########################################################
+
+// Call frame information parameters.
+const (
+	cieVersion      = 1
+	cieID           = 0xFFFFFFFF
+	codeAlign       = 1
+	dataAlign       = -4
+	regSP           = 29
+	regRA           = 31
+	cfaNop          = 0x00
+	cfaAdvanceLoc1  = 0x02
+	cfaAdvanceLoc2  = 0x03
+	cfaAdvanceLoc4  = 0x04
+	cfaDefCFA       = 0x0C
+	cfaDefCFAReg    = 0x0D
+	cfaDefCFAOffset = 0x0E
+	cfaAdvanceLoc   = 0x40
+	cfaOffset       = 0x80
+)
+
+// Maximum number of instructions of function prologues.
+const maxPrologue = 32
+
+// encodeFrames encodes the call frame information of the given functions to
+// .debug_frame, based on their code in text (optional). The CFA (canonical
+// frame address) is the value of the stack pointer at function entry.
+//
+// The frame layout of the SYM describes the stack frame after the function
+// prologue, the length of which is not recorded. As such, the prologue of each
+// function is located in its code, and functions without code or a recognized
+// prologue are left out. The stack frame is presumed to be deallocated in the
+// delay slot of the return jump, as output by the compiler, so the CFA rules
+// of the prologue hold until the function returns.
+func encodeFrames(buf *buffer, funcs []*c.FuncDecl, ends map[*c.FuncDecl]uint32, text *Text) {
+	if len(funcs) == 0 || text == nil {
+		return
+	}
+	// Common information entry.
+	cie := &buffer{}
+	cie.u32(cieID)
+	cie.u8(cieVersion)
+	cie.str("") // augmentation
+	cie.uleb(codeAlign)
+	cie.sleb(dataAlign)
+	cie.u8(regRA)
+	// Initial instructions; CFA = $sp.
+	cie.u8(cfaDefCFA)
+	cie.uleb(regSP)
+	cie.uleb(0)
+	cie.pad(4, cfaNop)
+	cieOffset := uint32(buf.Len())
+	buf.u32(uint32(cie.Len()))
+	buf.Write(cie.Bytes())
+	// Frame description entries.
+	for _, f := range funcs {
+		if f.Frame == nil || f.Addr < text.Addr || uint64(ends[f]) > uint64(text.Addr)+uint64(len(text.Data)) {
+			continue
+		}
+		code := text.Data[f.Addr-text.Addr : ends[f]-text.Addr]
+		insts, ok := prologueFrame(f.Frame, code)
+		if !ok {
+			continue
+		}
+		fde := &buffer{}
+		fde.u32(cieOffset)
+		fde.u32(f.Addr)
+		fde.u32(ends[f] - f.Addr)
+		fde.Write(insts)
+		fde.pad(4, cfaNop)
+		buf.u32(uint32(fde.Len()))
+		buf.Write(fde.Bytes())
+	}
+}
+
+// prologueFrame returns the call frame instructions of the given stack frame,
+// as set up by the prologue of the given function code. The boolean return
+// value reports whether the prologue was recognized.
+//
+// The prologue allocates the stack frame (addiu $sp, $sp, -size), saves
+// registers in the stack frame (sw $reg, offset($sp)) and optionally sets up
+// the frame pointer (move $fp, $sp).
+func prologueFrame(frame *c.Frame, code []byte) ([]byte, bool) {
+	if frame.Size == 0 && frame.FP == regSP {
+		// Leaf function without stack frame; CFA = $sp throughout.
+		return nil, true
+	}
+	saved := make(map[c.Reg]bool)
+	for _, s := range frame.Saved {
+		saved[s.Reg] = true
+	}
+	insts := &buffer{}
+	var loc uint32
+	advance := func(offset uint32) {
+		delta := offset - loc
+		switch {
+		case delta < cfaAdvanceLoc:
+			insts.u8(cfaAdvanceLoc | uint8(delta))
+		case delta <= 0xFF:
+			insts.u8(cfaAdvanceLoc1)
+			insts.u8(uint8(delta))
+		case delta <= 0xFFFF:
+			insts.u8(cfaAdvanceLoc2)
+			insts.u16(uint16(delta))
+		default:
+			insts.u8(cfaAdvanceLoc4)
+			insts.u32(delta)
+		}
+		loc = offset
+	}
+	allocated, fpSet := false, frame.FP == regSP
+	for i := 0; i < maxPrologue && 4*i+4 <= len(code); i++ {
+		inst := binary.LittleEndian.Uint32(code[4*i:])
+		op, rs, rt, rd := inst>>26, c.Reg(inst>>21&0x1F), c.Reg(inst>>16&0x1F), c.Reg(inst>>11&0x1F)
+		imm, funct := int32(int16(inst)), inst&0x3F
+		next := uint32(4*i + 4)
+		switch {
+		case !allocated && op == opADDIU && rs == regSP && rt == regSP && imm == -int32(frame.Size):
+			// addiu $sp, $sp, -size
+			allocated = true
+			advance(next)
+			insts.u8(cfaDefCFAOffset)
+			insts.uleb(uint64(frame.Size))
+		case allocated && op == opSW && rs == regSP && saved[rt] && 0 <= imm && imm < int32(frame.Size):
+			// sw $reg, offset($sp); offset relative to the CFA, factored by the
+			// data alignment factor.
+			delete(saved, rt)
+			advance(next)
+			insts.u8(cfaOffset | uint8(rt))
+			insts.uleb(uint64((int32(frame.Size) - imm) / -dataAlign))
+		case allocated && !fpSet && isMoveSP(op, rs, rt, rd, funct, imm, frame.FP):
+			// move $fp, $sp
+			fpSet = true
+			advance(next)
+			insts.u8(cfaDefCFAReg)
+			insts.uleb(uint64(frame.FP))
+		}
+		if allocated && fpSet && len(saved) == 0 {
+			break
+		}
+	}
+	if !allocated || !fpSet {
+		return nil, false
+	}
+	return insts.Bytes(), true
+}
+
+// MIPS instruction opcodes and SPECIAL function codes.
+const (
+	opSPECIAL = 0x00
+	opADDIU   = 0x09
+	opSW      = 0x2B
+	functADDU = 0x21
+	functOR   = 0x25
+	regZero   = 0
+)
+
+// isMoveSP reports whether the given instruction copies $sp to the given
+// register (addu/or reg, $sp, $zero or addiu reg, $sp, 0).
+func isMoveSP(op uint32, rs, rt, rd c.Reg, funct uint32, imm int32, reg c.Reg) bool {
+	switch {
+	case op == opSPECIAL && (funct == functADDU || funct == functOR) && rd == reg:
+		return rs == regSP && rt == regZero || rs == regZero && rt == regSP
+	case op == opADDIU && rs == regSP && rt == reg:
+		return imm == 0
+	}
+	return false
+}
+
+// ### [ Encoding helpers ] ####################################################
+
+// A buffer is a byte buffer with little-endian encoding helpers.
+type buffer struct {
+	bytes.Buffer
+}
+
+// u8 appends an 8-bit value to the buffer.
+func (buf *buffer) u8(v uint8) {
+	buf.WriteByte(v)
+}
+
+// u16 appends a 16-bit value to the buffer.
+func (buf *buffer) u16(v uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	buf.Write(b[:])
+}
+
+// u32 appends a 32-bit value to the buffer.
+func (buf *buffer) u32(v uint32) {
+	buf.Write(appendU32(nil, v))
+}
+
+// uleb appends an unsigned LEB128 value to the buffer.
+func (buf *buffer) uleb(v uint64) {
+	buf.Write(appendUleb(nil, v))
+}
+
+// sleb appends a signed LEB128 value to the buffer.
+func (buf *buffer) sleb(v int64) {
+	buf.Write(appendSleb(nil, v))
+}
+
+// str appends a NULL-terminated string to the buffer.
+func (buf *buffer) str(s string) {
+	buf.WriteString(s)
+	buf.WriteByte(0)
+}
+
+// pad pads the buffer with the given byte, so that its length plus the 4 byte
+// length field preceding it is a multiple of align.
+func (buf *buffer) pad(align int, b byte) {
+	for (4+buf.Len())%align != 0 {
+		buf.WriteByte(b)
+	}
+}
+
+// appendU32 appends a little-endian 32-bit value to b.
+func appendU32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// appendUleb appends an unsigned LEB128 value to b.
+func appendUleb(b []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// appendSleb appends a signed LEB128 value to b.
+func appendSleb(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7F)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}